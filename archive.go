@@ -0,0 +1,223 @@
+package srcscan
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ArchiveFS returns a FileSystem holding the contents of the archive file at path, so that it can
+// be scanned in place (without extracting it to disk) by setting Config.FileSystem. Paths in the
+// FileSystem are relative to the root of the archive. The format of the archive is determined
+// from its filename extension:
+//
+//	.zip, .jar, .war, .ear, .egg, .whl    zip archive
+//	.tar                                  tar archive
+//	.tar.gz, .tgz                         gzip-compressed tar archive (e.g., an npm package)
+//	.gem                                  Ruby gem (the contents of its data.tar.gz; see below)
+//
+// If a gem doesn't include a .gemspec file at its root, its FileSystem holds one synthesized from
+// the name and version in the gem's metadata.gz (without any dependencies), so that the gem is
+// scanned as a RubyGem.
+func ArchiveFS(path string) (FileSystem, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case hasAnySuffix([]string{".zip", ".jar", ".war", ".ear", ".egg", ".whl"}, name):
+		return ZipFS(bytes.NewReader(data), int64(len(data)))
+	case strings.HasSuffix(name, ".tar"):
+		return TarFS(bytes.NewReader(data))
+	case hasAnySuffix([]string{".tar.gz", ".tgz"}, name):
+		return tarGzFS(bytes.NewReader(data))
+	case strings.HasSuffix(name, ".gem"):
+		return gemFS(bytes.NewReader(data))
+	}
+	return nil, errors.New("unrecognized archive format: " + path)
+}
+
+// ZipFS returns a FileSystem holding the contents of the zip archive read from r, which has the
+// given size. Files are read from r when they are opened, so r must remain readable while the
+// FileSystem is in use.
+func ZipFS(r io.ReaderAt, size int64) (FileSystem, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]treeFile, len(zr.File))
+	for _, zf := range zr.File {
+		if strings.HasSuffix(zf.Name, "/") || zf.Mode().IsDir() {
+			continue // directories are implied by the files in them
+		}
+		files[zf.Name] = treeFile{
+			size: int64(zf.UncompressedSize64),
+			mode: zf.Mode(),
+			open: zf.Open,
+		}
+	}
+	return newTreeFS(files), nil
+}
+
+// TarFS returns a FileSystem holding the contents of the (uncompressed) tar archive read from r.
+// The regular files in the archive are read into memory; symbolic links are included but not
+// followed, and other special files are omitted.
+func TarFS(r io.Reader) (FileSystem, error) {
+	files, err := tarFiles(r)
+	if err != nil {
+		return nil, err
+	}
+	return newTreeFS(files), nil
+}
+
+// tarFiles reads the files of the tar archive read from r, as for TarFS.
+func tarFiles(r io.Reader) (map[string]treeFile, error) {
+	tr := tar.NewReader(r)
+	files := make(map[string]treeFile)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var data []byte
+		mode := os.FileMode(hdr.Mode) & os.ModePerm
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			if data, err = ioutil.ReadAll(tr); err != nil {
+				return nil, err
+			}
+		case tar.TypeSymlink:
+			data = []byte(hdr.Linkname)
+			mode |= os.ModeSymlink
+		default:
+			continue
+		}
+		files[hdr.Name] = memTreeFile(data, mode)
+	}
+	return files, nil
+}
+
+// memTreeFile returns a treeFile with the given contents and mode.
+func memTreeFile(data []byte, mode os.FileMode) treeFile {
+	return treeFile{
+		size: int64(len(data)),
+		mode: mode,
+		open: func() (io.ReadCloser, error) { return ioutil.NopCloser(bytes.NewReader(data)), nil },
+	}
+}
+
+func tarGzFS(r io.Reader) (FileSystem, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return TarFS(zr)
+}
+
+// gemFS returns a FileSystem holding the files of the Ruby gem read from r. A gem is a tar archive
+// whose data.tar.gz member holds the gem's files and whose metadata.gz member holds its
+// specification, in YAML. Gems often don't include their .gemspec file, so if there is none at the
+// root of the gem, one is synthesized from the name and version in the specification (so that the
+// gem is scanned as a RubyGem). The synthesized .gemspec declares no dependencies.
+func gemFS(r io.Reader) (FileSystem, error) {
+	tr := tar.NewReader(r)
+	var files map[string]treeFile
+	var metadata []byte
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch hdr.Name {
+		case "data.tar.gz":
+			zr, err := gzip.NewReader(tr)
+			if err != nil {
+				return nil, err
+			}
+			files, err = tarFiles(zr)
+			zr.Close()
+			if err != nil {
+				return nil, err
+			}
+		case "metadata.gz":
+			zr, err := gzip.NewReader(tr)
+			if err != nil {
+				return nil, err
+			}
+			metadata, err = ioutil.ReadAll(zr)
+			zr.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if files == nil {
+		return nil, errors.New("gem has no data.tar.gz")
+	}
+
+	for name := range files {
+		if !strings.Contains(name, "/") && strings.HasSuffix(name, ".gemspec") {
+			return newTreeFS(files), nil
+		}
+	}
+	if name, version := gemMetadataNameVersion(metadata); name != "" {
+		spec := "# Synthesized by srcscan from the gem's metadata.gz.\n" +
+			"Gem::Specification.new do |s|\n" +
+			"  s.name = " + strconv.Quote(name) + "\n"
+		if version != "" {
+			spec += "  s.version = " + strconv.Quote(version) + "\n"
+		}
+		spec += "end\n"
+		files[name+".gemspec"] = memTreeFile([]byte(spec), 0644)
+	}
+	return newTreeFS(files), nil
+}
+
+// gemMetadataNameVersion returns the name and version in the YAML specification of a gem (its
+// metadata.gz), in which they are the top-level "name" property and the "version" property of the
+// top-level "version" object.
+func gemMetadataNameVersion(metadata []byte) (name, version string) {
+	inVersion := false
+	for _, line := range strings.Split(string(metadata), "\n") {
+		trimmed := strings.TrimSpace(line)
+		topLevel := trimmed != "" && trimmed == line
+		switch {
+		case topLevel && strings.HasPrefix(line, "name:"):
+			name = yamlScalar(strings.TrimPrefix(line, "name:"))
+		case topLevel:
+			inVersion = strings.HasPrefix(line, "version:")
+		case inVersion && strings.HasPrefix(trimmed, "version:"):
+			version = yamlScalar(strings.TrimPrefix(trimmed, "version:"))
+			inVersion = false
+		}
+	}
+	return name, version
+}
+
+// yamlScalar returns the value of a plain or quoted YAML scalar.
+func yamlScalar(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,176 @@
+package srcscan
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"github.com/kr/pretty"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var archiveTestFiles = []struct{ name, data string }{
+	{"package/package.json", `{"name":"mypkg"}`},
+	{"package/lib/a.js", ""},
+	{"package/test/b.js", ""},
+}
+
+var archiveTestUnits = []Unit{&NPMPackage{
+	Dir:         "package",
+	PackageJSON: []byte(`{"name":"mypkg"}`),
+	LibFiles:    []string{"lib/a.js"},
+	TestFiles:   []string{"test/b.js"},
+}}
+
+func scanArchiveFS(t *testing.T, fs FileSystem) []Unit {
+	config := Default
+	config.Base = ""
+	config.FileSystem = fs
+	units, err := config.Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return units
+}
+
+func TestZipFS(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range archiveTestFiles {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, err := ZipFS(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if units := scanArchiveFS(t, fs); !reflect.DeepEqual(archiveTestUnits, units) {
+		t.Errorf("got units %+v, want %+v", units, archiveTestUnits)
+	}
+}
+
+// writeTar writes a tar archive of files to w.
+func writeTar(t *testing.T, w io.Writer, files []struct{ name, data string }) {
+	tw := tar.NewWriter(w)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.data)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// gzipped returns data compressed with gzip.
+func gzipped(t *testing.T, data []byte) string {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// archiveFS writes data to a temporary file with the given name and returns its ArchiveFS.
+func archiveFS(t *testing.T, name string, data []byte) FileSystem {
+	dir, err := ioutil.TempDir("", "srcscan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	fs, err := ArchiveFS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func TestArchiveFS_Tar(t *testing.T) {
+	var buf bytes.Buffer
+	writeTar(t, &buf, archiveTestFiles)
+	fs := archiveFS(t, "mypkg.tar", buf.Bytes())
+	if units := scanArchiveFS(t, fs); !reflect.DeepEqual(archiveTestUnits, units) {
+		t.Errorf("got units %+v, want %+v", units, archiveTestUnits)
+	}
+}
+
+func TestArchiveFS_TarGz(t *testing.T) {
+	var buf bytes.Buffer
+	writeTar(t, &buf, archiveTestFiles)
+	fs := archiveFS(t, "mypkg-1.0.0.tgz", []byte(gzipped(t, buf.Bytes())))
+	if units := scanArchiveFS(t, fs); !reflect.DeepEqual(archiveTestUnits, units) {
+		t.Errorf("got units %+v, want %+v", units, archiveTestUnits)
+	}
+}
+
+func TestArchiveFS_Gem(t *testing.T) {
+	metadata := `--- !ruby/object:Gem::Specification
+name: foo
+version: !ruby/object:Gem::Version
+  version: 1.2.0
+platform: ruby
+dependencies:
+- !ruby/object:Gem::Dependency
+  name: rake
+`
+	gem := func(files ...struct{ name, data string }) []byte {
+		var data, buf bytes.Buffer
+		writeTar(t, &data, files)
+		writeTar(t, &buf, []struct{ name, data string }{
+			{"metadata.gz", gzipped(t, []byte(metadata))},
+			{"data.tar.gz", gzipped(t, data.Bytes())},
+		})
+		return buf.Bytes()
+	}
+
+	// The gem doesn't include its .gemspec, so one is synthesized from its metadata.gz.
+	fs := archiveFS(t, "foo-1.2.0.gem", gem(
+		struct{ name, data string }{"lib/foo.rb", ""},
+		struct{ name, data string }{"lib/foo/version.rb", ""},
+	))
+	want := []Unit{&RubyGem{Dir: ".", Name: "foo", GemSpecFile: "foo.gemspec", SrcFiles: []string{"lib/foo/version.rb", "lib/foo.rb"}}}
+	if units := scanArchiveFS(t, fs); !reflect.DeepEqual(want, units) {
+		t.Errorf("units:\n%v", strings.Join(pretty.Diff(want, units), "\n"))
+	}
+	if data, err := readFile(fs, "foo.gemspec"); err != nil || !bytes.Contains(data, []byte(`s.version = "1.2.0"`)) {
+		t.Errorf("got synthesized gemspec %q (error %v), want version 1.2.0", data, err)
+	}
+
+	// The gem's own .gemspec is used if it includes one.
+	fs = archiveFS(t, "foo-1.2.0.gem", gem(
+		struct{ name, data string }{"foo.gemspec", "Gem::Specification.new do |s|\n  s.add_dependency 'json'\nend\n"},
+		struct{ name, data string }{"lib/foo.rb", ""},
+	))
+	want = []Unit{&RubyGem{Dir: ".", Name: "foo", GemSpecFile: "foo.gemspec", SrcFiles: []string{"lib/foo.rb"},
+		Deps: []Dependency{{Name: "json", Scope: ScopeRuntime, Ecosystem: "rubygems"}}}}
+	if units := scanArchiveFS(t, fs); !reflect.DeepEqual(want, units) {
+		t.Errorf("units:\n%v", strings.Join(pretty.Diff(want, units), "\n"))
+	}
+}
@@ -6,9 +6,14 @@ import (
 	"fmt"
 	"github.com/sourcegraph/srcscan"
 	"os"
+	"strings"
 )
 
 var verbose = flag.Bool("v", false, "show verbose output")
+var stats = flag.Bool("stats", false, "print scan statistics to stderr")
+var tree = flag.Bool("tree", false, "print units indented below their nearest enclosing unit")
+var profiles = flag.String("profiles", "", "load additional profiles from this JSON file")
+var graph = flag.String("graph", "", "print the dependency graph between units instead of the units, as \"dot\" or \"json\"")
 
 func main() {
 	flag.Usage = func() {
@@ -26,25 +31,77 @@ func main() {
 		dirs = flag.Args()
 	}
 
+	if *graph != "" && *graph != "dot" && *graph != "json" {
+		fmt.Fprintf(os.Stderr, "error: -graph must be \"dot\" or \"json\"\n")
+		os.Exit(1)
+	}
+
+	config := srcscan.Default
+	config.Stats = *stats
+	config.RepoConfig = true
+	if *profiles != "" {
+		if err := config.LoadProfilesFile(*profiles); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+	}
 	for i, dir := range dirs {
-		units, err := srcscan.Scan(dir)
+		r, err := config.ScanResult(dir)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %s\n", err)
 			os.Exit(1)
 		}
+		if *graph != "" {
+			if err := writeGraph(r.DependencyGraph()); err != nil {
+				fmt.Fprintf(os.Stderr, "error writing graph: %s\n", err)
+				os.Exit(1)
+			}
+			if r.Stats != nil {
+				fmt.Fprintf(os.Stderr, "%s:\n%s", dir, r.Stats)
+			}
+			continue
+		}
+
+		units := r.Units
+		depths := make([]int, len(units))
+		if *tree {
+			units = units[:0:0]
+			for _, root := range r.Tree() {
+				root.Walk(func(node *srcscan.UnitNode, depth int) {
+					units = append(units, node.Unit)
+					depths[len(units)-1] = depth
+				})
+			}
+		}
 		for j, unit := range units {
-			fmt.Printf("%-15s %s\n", srcscan.UnitType(unit), unit.Path())
+			indent := strings.Repeat("  ", depths[j])
+			fmt.Printf("%s%-15s %s\n", indent, srcscan.UnitType(unit), unit.Path())
 			if *verbose {
-				out, err := json.MarshalIndent(unit, "    ", "  ")
+				out, err := json.MarshalIndent(unit, indent+"    ", "  ")
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "error serializing to JSON: %s\n", err)
 					os.Exit(1)
 				}
-				fmt.Printf("    %s\n", out)
+				fmt.Printf("%s    %s\n", indent, out)
 				if i != len(dirs)-1 || j != len(units)-1 {
 					fmt.Printf("\n")
 				}
 			}
 		}
+		if r.Stats != nil {
+			fmt.Fprintf(os.Stderr, "%s:\n%s", dir, r.Stats)
+		}
+	}
+}
+
+func writeGraph(g *srcscan.Graph) error {
+	if *graph == "dot" {
+		return g.WriteDOT(os.Stdout)
+	}
+	out, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
 	}
+	_, err = fmt.Printf("%s\n", out)
+	return err
 }
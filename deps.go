@@ -0,0 +1,328 @@
+package srcscan
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A Dependency is a dependency that a source unit declares (such as in its package.json).
+type Dependency struct {
+	Name      string // name of the depended-on package (for Maven, "groupId:artifactId")
+	Version   string `json:",omitempty"` // version constraint, as declared (such as "~1.2.0")
+	Scope     string // one of the Scope constants
+	Ecosystem string // package ecosystem (see Metadata)
+}
+
+// Dependency scopes.
+const (
+	ScopeRuntime  = "runtime"
+	ScopeDev      = "dev"
+	ScopeTest     = "test"
+	ScopeOptional = "optional"
+)
+
+// A DependencyUnit is a Unit that declares dependencies. NPMPackage, BowerComponent, GoPackage,
+// JavaProject, RubyGem and RubyApp are DependencyUnits.
+type DependencyUnit interface {
+	Unit
+
+	// Dependencies returns the dependencies that the unit declares, sorted by scope and then by
+	// name.
+	Dependencies() []Dependency
+}
+
+// UnitDependencies returns the declared dependencies of unit, or nil if it is not a
+// DependencyUnit.
+func UnitDependencies(unit Unit) []Dependency {
+	if u, ok := unit.(DependencyUnit); ok {
+		return u.Dependencies()
+	}
+	return nil
+}
+
+func (u *NPMPackage) Dependencies() []Dependency {
+	return manifestDependencies(u.PackageJSON, "npm", map[string]string{
+		"dependencies":         ScopeRuntime,
+		"peerDependencies":     ScopeRuntime,
+		"devDependencies":      ScopeDev,
+		"optionalDependencies": ScopeOptional,
+	})
+}
+
+func (u *BowerComponent) Dependencies() []Dependency {
+	return manifestDependencies(u.BowerJSON, "bower", map[string]string{
+		"dependencies":    ScopeRuntime,
+		"devDependencies": ScopeDev,
+	})
+}
+
+// manifestDependencies returns the dependencies declared in the properties of a JSON manifest
+// (such as package.json) that map dependency names to versions. scopes maps the names of the
+// properties to read to the scope of their dependencies.
+func manifestDependencies(manifest []byte, ecosystem string, scopes map[string]string) []Dependency {
+	var m map[string]json.RawMessage
+	if json.Unmarshal(manifest, &m) != nil {
+		return nil
+	}
+	var deps []Dependency
+	for prop, scope := range scopes {
+		var versions map[string]string
+		if json.Unmarshal(m[prop], &versions) != nil {
+			continue
+		}
+		for name, version := range versions {
+			deps = append(deps, Dependency{Name: name, Version: version, Scope: scope, Ecosystem: ecosystem})
+		}
+	}
+	sortDependencies(deps)
+	return deps
+}
+
+// Dependencies returns the packages that the package imports, other than those in the standard
+// library (which are taken to be the import paths whose first element has no dot) and "C". Imports
+// that only its tests have are in ScopeTest.
+func (u *GoPackage) Dependencies() []Dependency {
+	var deps []Dependency
+	for _, dep := range u.imports() {
+		if strings.Contains(strings.SplitN(dep.Name, "/", 2)[0], ".") {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// imports returns all of the packages that the package and its tests import.
+func (u *GoPackage) imports() []Dependency {
+	var deps []Dependency
+	seen := make(map[string]bool)
+	add := func(imports []string, scope string) {
+		for _, path := range imports {
+			if !seen[path] {
+				seen[path] = true
+				deps = append(deps, Dependency{Name: path, Scope: scope, Ecosystem: "go"})
+			}
+		}
+	}
+	add(u.Imports, ScopeRuntime)
+	add(u.TestImports, ScopeTest)
+	add(u.XTestImports, ScopeTest)
+	sortDependencies(deps)
+	return deps
+}
+
+// Dependencies returns the dependencies declared in the pom.xml.
+func (u *JavaProject) Dependencies() []Dependency { return u.Deps }
+
+// Dependencies returns the dependencies declared in the .gemspec and Gemfile.
+func (u *RubyGem) Dependencies() []Dependency { return u.Deps }
+
+// Dependencies returns the dependencies declared in the Gemfile.
+func (u *RubyApp) Dependencies() []Dependency { return u.Deps }
+
+var _, _, _, _, _, _ DependencyUnit = &NPMPackage{}, &BowerComponent{}, &GoPackage{}, &JavaProject{}, &RubyGem{}, &RubyApp{}
+
+// sortDependencies sorts deps by scope and name.
+func sortDependencies(deps []Dependency) { sort.Sort(dependencies(deps)) }
+
+type dependencies []Dependency
+
+func (d dependencies) Len() int      { return len(d) }
+func (d dependencies) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d dependencies) Less(i, j int) bool {
+	if d[i].Scope != d[j].Scope {
+		return d[i].Scope < d[j].Scope
+	}
+	return d[i].Name < d[j].Name
+}
+
+// A pom is the part of a Maven pom.xml that srcscan reads.
+type pom struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Parent     struct {
+		GroupID string `xml:"groupId"`
+		Version string `xml:"version"`
+	} `xml:"parent"`
+	Dependencies []struct {
+		GroupID    string `xml:"groupId"`
+		ArtifactID string `xml:"artifactId"`
+		Version    string `xml:"version"`
+		Scope      string `xml:"scope"`
+		Optional   string `xml:"optional"`
+	} `xml:"dependencies>dependency"`
+}
+
+// readPOM reads the pom.xml in dir. A pom.xml that can't be parsed is treated as empty.
+func readPOM(config Config, dir string) (*pom, error) {
+	data, err := readManifest(config, dir, "pom.xml")
+	if err != nil {
+		return nil, err
+	}
+	var p pom
+	if xml.Unmarshal(data, &p) != nil {
+		return &pom{}, nil
+	}
+	if p.GroupID == "" {
+		p.GroupID = p.Parent.GroupID
+	}
+	if p.Version == "" {
+		p.Version = p.Parent.Version
+	}
+	return &p, nil
+}
+
+// dependencies returns the dependencies declared in p.
+func (p *pom) dependencies() []Dependency {
+	var deps []Dependency
+	for _, d := range p.Dependencies {
+		dep := Dependency{Name: d.GroupID + ":" + d.ArtifactID, Version: d.Version, Scope: ScopeRuntime, Ecosystem: "maven"}
+		switch {
+		case strings.TrimSpace(d.Optional) == "true":
+			dep.Scope = ScopeOptional
+		case strings.TrimSpace(d.Scope) == "test":
+			dep.Scope = ScopeTest
+		}
+		deps = append(deps, dep)
+	}
+	sortDependencies(deps)
+	return deps
+}
+
+// rubyStringLiteral matches a Ruby string literal without interpolation: a quoted string or a
+// %q string (such as %q<rails>, as in generated gemspecs), optionally frozen.
+const rubyStringLiteral = `(?:["']([^"']*)["']|%[qQ]?<([^>]*)>|%[qQ]?\(([^)]*)\)|%[qQ]?\{([^}]*)\}|%[qQ]?\[([^\]]*)\])(?:\.freeze)?`
+
+var (
+	gemspecDependency = regexp.MustCompile(`\.add_(runtime_|development_)?dependency[\s(]+(.*)`)
+	gemfileGem        = regexp.MustCompile(`^gem[\s(]+(.*)`)
+	gemfileGroup      = regexp.MustCompile(`^group[\s(]+(.*?)\)?\s+do\b`)
+	gemfileGemGroup   = regexp.MustCompile(`(?:group:|:group\s*=>)\s*\[?\s*:(\w+)`)
+	rubyBlockStart    = regexp.MustCompile(`\bdo(\s*\|[^|]*\|)?\s*$`)
+	rubyKeywordStart  = regexp.MustCompile(`(?:^|=\s*)(?:if|unless|case|begin|while|until|for|def|class|module)\b`)
+	rubyString        = regexp.MustCompile(`^\s*` + rubyStringLiteral)
+	rubyStrings       = regexp.MustCompile(`^\s*,\s*\[?\s*` + rubyStringLiteral)
+)
+
+// readRubyDependencies reads the dependencies declared in the named .gemspec file (if gemspec is
+// not empty) and the Gemfile (if there is one) in dir.
+func readRubyDependencies(config Config, dir, gemspec string) ([]Dependency, error) {
+	var deps []Dependency
+	if gemspec != "" {
+		data, err := readManifest(config, dir, gemspec)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, parseGemspecDependencies(data)...)
+	}
+	data, err := readManifest(config, dir, "Gemfile")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	deps = append(deps, parseGemfileDependencies(data)...)
+	sortDependencies(deps)
+	return deps, nil
+}
+
+func parseGemspecDependencies(data []byte) []Dependency {
+	var deps []Dependency
+	for _, m := range gemspecDependency.FindAllSubmatch(data, -1) {
+		name, version, ok := rubyArgs(string(m[2]))
+		if !ok {
+			continue
+		}
+		scope := ScopeRuntime
+		if string(m[1]) == "development_" {
+			scope = ScopeDev
+		}
+		deps = append(deps, Dependency{Name: name, Version: version, Scope: scope, Ecosystem: "rubygems"})
+	}
+	return deps
+}
+
+// parseGemfileDependencies returns the gems declared in a Gemfile. Gems in the development and test
+// groups are in ScopeDev and ScopeTest; all other gems are in ScopeRuntime.
+func parseGemfileDependencies(data []byte) []Dependency {
+	var deps []Dependency
+	scopes := []string{ScopeRuntime} // scopes of the enclosing blocks
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if i := strings.Index(line, "#"); i != -1 && !strings.ContainsAny(line[:i], `"'`) {
+			line = strings.TrimSpace(line[:i])
+		}
+		scope := scopes[len(scopes)-1]
+		switch {
+		case line == "end":
+			if len(scopes) > 1 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		case gemfileGroup.MatchString(line):
+			scopes = append(scopes, gemfileScope(gemfileGroup.FindStringSubmatch(line)[1], scope))
+		case rubyBlockStart.MatchString(line), rubyKeywordStart.MatchString(line):
+			// A block or a compound statement (such as an if statement) also ends with "end".
+			scopes = append(scopes, scope)
+		case gemfileGem.MatchString(line):
+			args := gemfileGem.FindStringSubmatch(line)[1]
+			name, version, ok := rubyArgs(args)
+			if !ok {
+				continue
+			}
+			if m := gemfileGemGroup.FindStringSubmatch(args); m != nil {
+				scope = gemfileScope(":"+m[1], scope)
+			}
+			deps = append(deps, Dependency{Name: name, Version: version, Scope: scope, Ecosystem: "rubygems"})
+		}
+	}
+	return deps
+}
+
+// gemfileScope returns the scope of the gems in the Gemfile groups listed in groups (such as
+// ":development, :test"), which are in a block whose scope is scope.
+func gemfileScope(groups, scope string) string {
+	switch group := strings.TrimSpace(strings.SplitN(groups, ",", 2)[0]); group {
+	case ":development", ":dev":
+		return ScopeDev
+	case ":test":
+		return ScopeTest
+	}
+	return scope
+}
+
+// rubyArgs parses the leading string arguments of a Ruby method call, such as `"rails", "~> 4.0",
+// ">= 4.0.1"` or `%q<rails>.freeze, ["~> 4.0", ">= 4.0.1"]`, into a name and a comma-separated
+// version constraint.
+func rubyArgs(args string) (name, version string, ok bool) {
+	m := rubyString.FindStringSubmatchIndex(args)
+	if m == nil {
+		return "", "", false
+	}
+	name = rubyStringValue(args, m)
+	var versions []string
+	for rest := args[m[1]:]; ; {
+		v := rubyStrings.FindStringSubmatchIndex(rest)
+		if v == nil {
+			break
+		}
+		versions = append(versions, rubyStringValue(rest, v))
+		rest = rest[v[1]:]
+	}
+	return name, strings.Join(versions, ", "), true
+}
+
+// rubyStringValue returns the contents of the string literal matched by rubyString or
+// rubyStrings in s, given the submatch indexes m.
+func rubyStringValue(s string, m []int) string {
+	for i := 2; i+1 < len(m); i += 2 {
+		if m[i] >= 0 {
+			return s[m[i]:m[i+1]]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package srcscan
+
+import (
+	"github.com/kr/pretty"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnitDependencies(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.FileSystem = MapFS(map[string]string{
+		"js/package.json": `{"name":"a","dependencies":{"lodash":"^4.0.0"},"devDependencies":{"mocha":"*"},"optionalDependencies":{"fsevents":"1.x"}}`,
+		"go/a.go":         "package a\n\nimport (\n\t\"fmt\"\n\t\"github.com/x/y\"\n)\n",
+		"go/a_test.go":    "package a\n\nimport (\n\t\"testing\"\n\t\"github.com/x/y\"\n\t\"github.com/x/z/assert\"\n)\n",
+		"java/pom.xml": `<project>
+  <dependencies>
+    <dependency><groupId>junit</groupId><artifactId>junit</artifactId><version>4.12</version><scope>test</scope></dependency>
+    <dependency><groupId>com.google.guava</groupId><artifactId>guava</artifactId><version>19.0</version></dependency>
+  </dependencies>
+  <dependencyManagement><dependencies>
+    <dependency><groupId>org.slf4j</groupId><artifactId>slf4j-api</artifactId><version>1.7.21</version></dependency>
+  </dependencies></dependencyManagement>
+</project>`,
+		"ruby/gem/g.gemspec": `Gem::Specification.new do |s|
+  s.name = "g"
+  s.add_dependency "json", "~> 1.8"
+  s.add_development_dependency('rake', '>= 10', '< 12')
+  s.add_runtime_dependency(%q<rails>.freeze, [">= 4", "< 6"])
+end
+`,
+		"ruby/gem/Gemfile":   "source 'https://rubygems.org'\ngemspec\n",
+		"ruby/app/config.ru": "",
+		"ruby/app/Gemfile": `source 'https://rubygems.org'
+gem 'rails', '4.2.0' # web framework
+gem "pg"
+group :development, :test do
+  gem 'byebug'
+end
+group :test do
+  platforms :ruby do
+    gem 'capybara', '~> 2.4'
+  end
+  if ENV['CI']
+    gem 'simplecov'
+  end
+  gem 'webmock'
+end
+gem 'rspec-rails', group: :test
+`,
+	})
+	config.Profiles = nil
+
+	units, err := config.Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]Dependency)
+	for _, unit := range units {
+		if deps := UnitDependencies(unit); deps != nil {
+			got[unit.Path()] = deps
+		}
+	}
+	want := map[string][]Dependency{
+		"js": {
+			{Name: "mocha", Version: "*", Scope: ScopeDev, Ecosystem: "npm"},
+			{Name: "fsevents", Version: "1.x", Scope: ScopeOptional, Ecosystem: "npm"},
+			{Name: "lodash", Version: "^4.0.0", Scope: ScopeRuntime, Ecosystem: "npm"},
+		},
+		"go": {
+			{Name: "github.com/x/y", Scope: ScopeRuntime, Ecosystem: "go"},
+			{Name: "github.com/x/z/assert", Scope: ScopeTest, Ecosystem: "go"},
+		},
+		"java": {
+			{Name: "com.google.guava:guava", Version: "19.0", Scope: ScopeRuntime, Ecosystem: "maven"},
+			{Name: "junit:junit", Version: "4.12", Scope: ScopeTest, Ecosystem: "maven"},
+		},
+		"ruby/gem": {
+			{Name: "rake", Version: ">= 10, < 12", Scope: ScopeDev, Ecosystem: "rubygems"},
+			{Name: "json", Version: "~> 1.8", Scope: ScopeRuntime, Ecosystem: "rubygems"},
+			{Name: "rails", Version: ">= 4, < 6", Scope: ScopeRuntime, Ecosystem: "rubygems"},
+		},
+		"ruby/app": {
+			{Name: "byebug", Scope: ScopeDev, Ecosystem: "rubygems"},
+			{Name: "pg", Scope: ScopeRuntime, Ecosystem: "rubygems"},
+			{Name: "rails", Version: "4.2.0", Scope: ScopeRuntime, Ecosystem: "rubygems"},
+			{Name: "capybara", Version: "~> 2.4", Scope: ScopeTest, Ecosystem: "rubygems"},
+			{Name: "rspec-rails", Scope: ScopeTest, Ecosystem: "rubygems"},
+			{Name: "simplecov", Scope: ScopeTest, Ecosystem: "rubygems"},
+			{Name: "webmock", Scope: ScopeTest, Ecosystem: "rubygems"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got dependencies %+v, want %+v\n%s", got, want, strings.Join(pretty.Diff(got, want), "\n"))
+	}
+}
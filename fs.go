@@ -0,0 +1,284 @@
+package srcscan
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A FileSystem provides read-only access to a tree of files. Scans read from the operating
+// system's file system unless Config.FileSystem is set.
+//
+// Paths passed to the methods of a FileSystem are formed by joining the directory passed to Scan
+// with the names returned by ReadDir (using package path/filepath).
+type FileSystem interface {
+	// Lstat returns a FileInfo describing the named file. If the file is a symbolic link, the
+	// FileInfo describes the link itself.
+	Lstat(path string) (os.FileInfo, error)
+
+	// Stat returns a FileInfo describing the named file, following symbolic links.
+	Stat(path string) (os.FileInfo, error)
+
+	// ReadDir returns FileInfos (as Lstat would return them) for the entries of the named
+	// directory, sorted by name.
+	ReadDir(path string) ([]os.FileInfo, error)
+
+	// Open opens the named file for reading.
+	Open(path string) (io.ReadCloser, error)
+}
+
+// OS is the FileSystem of the operating system.
+var OS FileSystem = osFS{}
+
+type osFS struct{}
+
+func (osFS) Lstat(path string) (os.FileInfo, error)     { return os.Lstat(path) }
+func (osFS) Stat(path string) (os.FileInfo, error)      { return os.Stat(path) }
+func (osFS) ReadDir(path string) ([]os.FileInfo, error) { return ioutil.ReadDir(path) }
+func (osFS) Open(path string) (io.ReadCloser, error)    { return os.Open(path) }
+
+// fs returns the FileSystem to scan, from which ignored files are omitted if c.RespectGitIgnore
+// is set, and which counts the bytes read if c.Stats is set. During a scan, it is a scanFS.
+func (c Config) fs() FileSystem {
+	fs := c.FileSystem
+	if fs == nil {
+		fs = OS
+	}
+	if c.stats != nil {
+		fs = countingFS{fs, c.stats}
+	}
+	if c.ignore != nil {
+		fs = ignoringFS{fs, c.ignore}
+	}
+	if c.scanDir != "" {
+		fs = scanFS{fs, c.scanDir}
+	}
+	return fs
+}
+
+// A scanFS is the FileSystem of a scan in progress, which knows the scanned directory, so that
+// matchers (such as PathGlob) can match paths relative to it.
+type scanFS struct {
+	FileSystem
+	dir string
+}
+
+// rel returns path relative to the scanned directory.
+func (fs scanFS) rel(path string) (string, error) {
+	dir := fs.dir
+	if filepath.IsAbs(dir) != filepath.IsAbs(path) {
+		// Unit readers are given absolute paths on the OS file system.
+		var err error
+		if dir, err = filepath.Abs(dir); err != nil {
+			return "", err
+		}
+		if path, err = filepath.Abs(path); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Rel(dir, path)
+}
+
+func readFile(fs FileSystem, path string) ([]byte, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// readFileLimit is like readFile, but if max is positive it reads at most max bytes of the file.
+// It reports whether the file is longer than that, in which case data holds only its first max
+// bytes. The limit is enforced on what is read, regardless of the size the file system reports.
+func readFileLimit(fs FileSystem, path string, max int64) (data []byte, truncated bool, err error) {
+	if max <= 0 {
+		data, err = readFile(fs, path)
+		return data, false, err
+	}
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	data, err = ioutil.ReadAll(io.LimitReader(f, max+1))
+	if int64(len(data)) > max {
+		return data[:max], true, err
+	}
+	return data, false, err
+}
+
+// walk walks the file tree rooted at root in fs, calling walkFn for each file or directory in the
+// tree, including root, in the same manner as filepath.Walk. Unlike filepath.Walk, it follows
+// root if it is a symbolic link, and walkFn may return errStopWalk to end the walk early.
+func walk(fs FileSystem, root string, walkFn filepath.WalkFunc) error {
+	info, err := fs.Stat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = walkDir(fs, root, info, walkFn)
+	}
+	if err == filepath.SkipDir || err == errStopWalk {
+		return nil
+	}
+	return err
+}
+
+func walkDir(fs FileSystem, path string, info os.FileInfo, walkFn filepath.WalkFunc) error {
+	err := walkFn(path, info, nil)
+	if err != nil {
+		if info.IsDir() && err == filepath.SkipDir {
+			return nil
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return nil
+	}
+
+	infos, err := fs.ReadDir(path)
+	if err != nil {
+		return walkFn(path, info, err)
+	}
+	for _, fi := range infos {
+		err = walkDir(fs, filepath.Join(path, fi.Name()), fi, walkFn)
+		if err != nil {
+			if !fi.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MapFS returns an in-memory FileSystem whose files are the keys of m (slash-separated paths
+// relative to the root of the file system) with the corresponding values as their contents.
+// Directories are implied by the file paths. The root may be referred to as "." or "".
+func MapFS(m map[string]string) FileSystem {
+	files := make(map[string]treeFile, len(m))
+	for name, data := range m {
+		data := data
+		files[name] = treeFile{
+			size: int64(len(data)),
+			open: func() (io.ReadCloser, error) { return ioutil.NopCloser(strings.NewReader(data)), nil },
+		}
+	}
+	return newTreeFS(files)
+}
+
+// treeFS is a FileSystem backed by an index of the files in a tree, such as an in-memory map,
+// a git tree or an archive.
+type treeFS struct {
+	files map[string]treeFile
+	dirs  map[string][]string // directory -> sorted names of entries
+}
+
+// A treeFile describes a file in a treeFS.
+type treeFile struct {
+	size int64
+	mode os.FileMode // permission bits and type (0 means a read-only regular file)
+	open func() (io.ReadCloser, error)
+}
+
+// newTreeFS returns a treeFS holding files, whose keys are slash-separated paths relative to the
+// root of the tree.
+func newTreeFS(files map[string]treeFile) *treeFS {
+	entries := map[string]map[string]bool{"": {}}
+	for name := range files {
+		for name = cleanTreePath(name); name != ""; name = cleanTreePath(path.Dir(name)) {
+			dir := cleanTreePath(path.Dir(name))
+			if entries[dir] == nil {
+				entries[dir] = map[string]bool{}
+			}
+			entries[dir][path.Base(name)] = true
+		}
+	}
+
+	fs := &treeFS{files: make(map[string]treeFile, len(files)), dirs: make(map[string][]string, len(entries))}
+	for name, f := range files {
+		fs.files[cleanTreePath(name)] = f
+	}
+	for dir, names := range entries {
+		list := make([]string, 0, len(names))
+		for name := range names {
+			list = append(list, name)
+		}
+		sort.Strings(list)
+		fs.dirs[dir] = list
+	}
+	return fs
+}
+
+func cleanTreePath(name string) string {
+	name = strings.Trim(path.Clean(filepath.ToSlash(name)), "/")
+	if name == "." {
+		return ""
+	}
+	return name
+}
+
+func (fs *treeFS) Lstat(name string) (os.FileInfo, error) {
+	name = cleanTreePath(name)
+	if _, isDir := fs.dirs[name]; isDir {
+		return treeFileInfo{name: path.Base("./" + name), mode: os.ModeDir | 0555}, nil
+	}
+	if f, isFile := fs.files[name]; isFile {
+		mode := f.mode
+		if mode == 0 {
+			mode = 0444
+		}
+		return treeFileInfo{name: path.Base(name), size: f.size, mode: mode}, nil
+	}
+	return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+}
+
+func (fs *treeFS) Stat(name string) (os.FileInfo, error) { return fs.Lstat(name) }
+
+func (fs *treeFS) ReadDir(name string) ([]os.FileInfo, error) {
+	name = cleanTreePath(name)
+	names, isDir := fs.dirs[name]
+	if !isDir {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: os.ErrNotExist}
+	}
+	infos := make([]os.FileInfo, len(names))
+	for i, elem := range names {
+		var err error
+		if infos[i], err = fs.Lstat(path.Join(name, elem)); err != nil {
+			return nil, err
+		}
+	}
+	return infos, nil
+}
+
+func (fs *treeFS) Open(name string) (io.ReadCloser, error) {
+	name = cleanTreePath(name)
+	f, isFile := fs.files[name]
+	if !isFile {
+		if _, isDir := fs.dirs[name]; isDir {
+			return nil, &os.PathError{Op: "open", Path: name, Err: errIsDir}
+		}
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return f.open()
+}
+
+var errIsDir = errors.New("is a directory")
+
+type treeFileInfo struct {
+	name string
+	size int64
+	mode os.FileMode
+}
+
+func (fi treeFileInfo) Name() string       { return fi.name }
+func (fi treeFileInfo) Size() int64        { return fi.size }
+func (fi treeFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi treeFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi treeFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi treeFileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,81 @@
+package srcscan
+
+import (
+	"github.com/kr/pretty"
+	"go/build"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestScan_MapFS(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.PathIndependent = true
+	config.FileSystem = MapFS(map[string]string{
+		"bower.json":               `{"name":"foo"}`,
+		"go/a.go":                  "package a\n\nimport \"fmt\"\n",
+		"go/a_test.go":             "package a\n",
+		"npm/package.json":         `{"name":"mypkg"}`,
+		"npm/lib/a.js":             "",
+		"npm/test/b.js":            "",
+		"python/mypkg/__init__.py": "",
+		"python/mypkg/a.py":        "",
+		"ruby/gem/x.gemspec":       "",
+		"ruby/gem/lib/x.rb":        "",
+	})
+
+	units, err := config.Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Unit{
+		&BowerComponent{Dir: ".", BowerJSON: []byte(`{"name":"foo"}`)},
+		&GoPackage{Package: build.Package{
+			Dir:          "go",
+			Name:         "a",
+			ImportPath:   ".",
+			GoFiles:      []string{"a.go"},
+			TestGoFiles:  []string{"a_test.go"},
+			Imports:      []string{"fmt"},
+			TestImports:  []string{},
+			XTestImports: []string{},
+		}},
+		&NPMPackage{
+			Dir:         "npm",
+			PackageJSON: []byte(`{"name":"mypkg"}`),
+			LibFiles:    []string{"lib/a.js"},
+			TestFiles:   []string{"test/b.js"},
+		},
+		&PythonPackage{Dir: "python/mypkg"},
+		&RubyGem{
+			Dir:         "ruby/gem",
+			Name:        "x",
+			GemSpecFile: "x.gemspec",
+			SrcFiles:    []string{"lib/x.rb"},
+		},
+	}
+
+	// Only compare the fields of GoPackage that are set above.
+	for _, unit := range units {
+		if u, ok := unit.(*GoPackage); ok {
+			u.Package = build.Package{
+				Dir:          u.Dir,
+				Name:         u.Name,
+				ImportPath:   u.ImportPath,
+				GoFiles:      u.GoFiles,
+				TestGoFiles:  u.TestGoFiles,
+				Imports:      u.Imports,
+				TestImports:  u.TestImports,
+				XTestImports: u.XTestImports,
+			}
+		}
+	}
+
+	sort.Sort(Units(units))
+	sort.Sort(Units(want))
+	if !reflect.DeepEqual(want, units) {
+		t.Errorf("units:\n%v", strings.Join(pretty.Diff(want, units), "\n"))
+	}
+}
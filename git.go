@@ -0,0 +1,93 @@
+package srcscan
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// GitTreeFS returns a FileSystem holding the tree of revision rev (a commit, tag, tree or any
+// other revision that git rev-parse understands) of the git repository at repoDir. The tree is
+// listed with the git binary when GitTreeFS is called, and files are read from the repository's
+// object store when they are opened; the working tree is never read or written.
+//
+// To scan a revision as Scan would scan a checkout of it, set Config.FileSystem to the returned
+// FileSystem and scan ".":
+//
+//	fs, err := srcscan.GitTreeFS("/path/to/repo", "v1.0")
+//	if err != nil {
+//		...
+//	}
+//	config := srcscan.Default
+//	config.FileSystem = fs
+//	units, err := config.Scan(".")
+//
+// Symbolic links are not followed, and submodules are omitted.
+func GitTreeFS(repoDir, rev string) (FileSystem, error) {
+	out, err := git(repoDir, "ls-tree", "-r", "-l", "-z", "--full-tree", rev)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]treeFile)
+	for _, entry := range bytes.Split(out, []byte{0}) {
+		if len(entry) == 0 {
+			continue
+		}
+
+		// Each entry is "<mode> SP <type> SP <object> SP+ <size> TAB <path>".
+		tab := bytes.IndexByte(entry, '\t')
+		if tab == -1 {
+			return nil, fmt.Errorf("git ls-tree %s: malformed entry %q", rev, entry)
+		}
+		fields := strings.Fields(string(entry[:tab]))
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("git ls-tree %s: malformed entry %q", rev, entry)
+		}
+		if fields[1] != "blob" {
+			continue // submodule
+		}
+		mode, err := strconv.ParseUint(fields[0], 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("git ls-tree %s: malformed mode in entry %q", rev, entry)
+		}
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("git ls-tree %s: malformed size in entry %q", rev, entry)
+		}
+
+		f := treeFile{size: size, mode: os.FileMode(mode & 0777)}
+		if mode&0170000 == 0120000 {
+			f.mode |= os.ModeSymlink
+		}
+		object := fields[2]
+		f.open = func() (io.ReadCloser, error) {
+			data, err := git(repoDir, "cat-file", "blob", object)
+			if err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+		files[string(entry[tab+1:])] = f
+	}
+	return newTreeFS(files), nil
+}
+
+// git runs git with args in dir and returns its output.
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			err = fmt.Errorf("%s (%s)", err, bytes.TrimSpace(ee.Stderr))
+		}
+		return nil, fmt.Errorf("git %s: %s", strings.Join(args, " "), err)
+	}
+	return out, nil
+}
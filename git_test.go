@@ -0,0 +1,71 @@
+package srcscan
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGitTreeFS(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	dir, err := ioutil.TempDir("", "srcscan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile := func(name, data string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("npm/package.json", `{"name":"mypkg"}`)
+	writeFile("npm/lib/a.js", "")
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		if _, err := git(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Change the working tree, which must not affect the scan.
+	writeFile("npm/package.json", `{"name":"changed"}`)
+	writeFile("npm/lib/b.js", "")
+
+	fs, err := GitTreeFS(dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := Default
+	config.Base = ""
+	config.FileSystem = fs
+	units, err := config.Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Unit{&NPMPackage{
+		Dir:         "npm",
+		PackageJSON: []byte(`{"name":"mypkg"}`),
+		LibFiles:    []string{"lib/a.js"},
+	}}
+	if !reflect.DeepEqual(want, units) {
+		t.Errorf("got units %+v, want %+v", units, want)
+	}
+
+	if _, err := GitTreeFS(dir, "nosuchrev"); err == nil {
+		t.Error("got no error for nonexistent revision")
+	}
+}
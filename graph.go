@@ -0,0 +1,170 @@
+package srcscan
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// A Graph is a graph of the dependencies between source units found in the same scan, as created
+// by DependencyGraph.
+type Graph struct {
+	Units []Unit
+	Edges []Edge // in the order of the units that declare the dependencies
+}
+
+// An Edge is a dependency of a source unit on another source unit.
+type Edge struct {
+	From, To   Unit
+	Dependency Dependency // the dependency of From that To satisfies
+
+	from, to int // indexes of From and To in Graph.Units
+}
+
+// DependencyGraph returns the graph of the dependencies between units. A unit depends on another
+// unit if one of its declared dependencies (see Dependencies) is in the same ecosystem and has the
+// name of the other unit: the import path of a GoPackage (derived from the nearest go.mod, or else
+// the GOPATH), the name in the package.json of an NPMPackage or the bower.json of a
+// BowerComponent, the groupId and artifactId of a JavaProject ("groupId:artifactId"), or the name
+// of a RubyGem. All of the imports of a GoPackage are considered, including those that
+// Dependencies omits. If several units have the name of a dependency, the dependency has an edge
+// to each of them.
+func DependencyGraph(units []Unit) *Graph {
+	byName := make(map[Dependency][]int)
+	for i, unit := range units {
+		if key, ok := unitKey(unit); ok {
+			byName[key] = append(byName[key], i)
+		}
+	}
+
+	g := &Graph{Units: units}
+	for i, unit := range units {
+		var deps []Dependency
+		if u, ok := unit.(*GoPackage); ok {
+			deps = u.imports()
+		} else {
+			deps = UnitDependencies(unit)
+		}
+		for _, dep := range deps {
+			for _, j := range byName[Dependency{Name: dep.Name, Ecosystem: dep.Ecosystem}] {
+				if j != i {
+					g.Edges = append(g.Edges, Edge{From: unit, To: units[j], Dependency: dep, from: i, to: j})
+				}
+			}
+		}
+	}
+	return g
+}
+
+// unitKey returns the name and ecosystem that the dependencies on unit declare.
+func unitKey(unit Unit) (key Dependency, ok bool) {
+	switch u := unit.(type) {
+	case *GoPackage:
+		key = Dependency{Name: u.ImportPath, Ecosystem: "go"}
+		if key.Name == "." {
+			key.Name = ""
+		}
+	case *NPMPackage:
+		key.Name, _ = manifestNameVersion(u.PackageJSON)
+		key.Ecosystem = "npm"
+	case *BowerComponent:
+		key.Name, _ = manifestNameVersion(u.BowerJSON)
+		key.Ecosystem = "bower"
+	case *JavaProject:
+		if u.ArtifactID != "" {
+			key = Dependency{Name: u.GroupID + ":" + u.ArtifactID, Ecosystem: "maven"}
+		}
+	case *RubyGem:
+		key = Dependency{Name: u.Name, Ecosystem: "rubygems"}
+	}
+	return key, key.Name != ""
+}
+
+// DependencyGraph returns the graph of the dependencies between the units found (see
+// DependencyGraph).
+func (r *Result) DependencyGraph() *Graph {
+	return DependencyGraph(r.Units)
+}
+
+// DependenciesOf returns the edges from unit to the units it depends on.
+func (g *Graph) DependenciesOf(unit Unit) []Edge {
+	var edges []Edge
+	for _, e := range g.Edges {
+		if sameUnit(e.From, unit) {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// DependentsOf returns the edges to unit from the units that depend on it.
+func (g *Graph) DependentsOf(unit Unit) []Edge {
+	var edges []Edge
+	for _, e := range g.Edges {
+		if sameUnit(e.To, unit) {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// sameUnit reports whether a and b are the same unit. A unit whose type isn't comparable is never
+// the same as another, rather than causing a panic.
+func sameUnit(a, b Unit) bool {
+	if t := reflect.TypeOf(a); t != reflect.TypeOf(b) || (t != nil && !t.Comparable()) {
+		return false
+	}
+	return a == b
+}
+
+// WriteDOT writes g to w in the Graphviz DOT language. Each unit is a node labeled with its type
+// and path, and each edge is labeled with the scope of its dependency, unless it is ScopeRuntime.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph units {")
+	for i, unit := range g.Units {
+		fmt.Fprintf(bw, "  u%d [label=%s];\n", i, dotQuote(UnitType(unit)+"\n"+unit.Path()))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "  u%d -> u%d", e.from, e.to)
+		if e.Dependency.Scope != ScopeRuntime {
+			fmt.Fprintf(bw, " [label=%s]", dotQuote(e.Dependency.Scope))
+		}
+		fmt.Fprintln(bw, ";")
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotQuote returns s as a DOT quoted string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// MarshalJSON encodes g as an object with a list of Units (each with its Type, as for
+// MarshalableUnit) and a list of Edges, whose From and To are indexes into Units.
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	type jsonEdge struct {
+		From, To   int
+		Dependency Dependency
+	}
+	v := struct {
+		Units []*MarshalableUnit
+		Edges []jsonEdge
+	}{
+		Units: make([]*MarshalableUnit, len(g.Units)),
+		Edges: make([]jsonEdge, len(g.Edges)),
+	}
+	for i, unit := range g.Units {
+		v.Units[i] = &MarshalableUnit{unit}
+	}
+	for i, e := range g.Edges {
+		v.Edges[i] = jsonEdge{e.from, e.to, e.Dependency}
+	}
+	return json.Marshal(v)
+}
+
+var _ json.Marshaler = &Graph{}
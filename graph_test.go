@@ -0,0 +1,137 @@
+package srcscan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"reflect"
+	"testing"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	units := []Unit{
+		&GoPackage{Package: build.Package{Dir: "cmd/x", ImportPath: "example.com/r/cmd/x", Imports: []string{"example.com/r/lib", "fmt"}}},
+		&GoPackage{Package: build.Package{Dir: "lib", ImportPath: "example.com/r/lib", TestImports: []string{"example.com/r/lib", "testing"}}},
+		&NPMPackage{Dir: "web", PackageJSON: []byte(`{"name":"web","dependencies":{"ui":"*","lodash":"*"},"devDependencies":{"testkit":"*"}}`)},
+		&NPMPackage{Dir: "ui", PackageJSON: []byte(`{"name":"ui"}`)},
+		&NPMPackage{Dir: "testkit", PackageJSON: []byte(`{"name":"testkit"}`)},
+		&BowerComponent{Dir: "ui", BowerJSON: []byte(`{"name":"ui"}`)},
+		&JavaProject{Dir: "app", GroupID: "com.example", ArtifactID: "app", Deps: []Dependency{
+			{Name: "com.example:core", Scope: ScopeRuntime, Ecosystem: "maven"},
+			{Name: "junit:junit", Scope: ScopeTest, Ecosystem: "maven"},
+		}},
+		&JavaProject{Dir: "core", GroupID: "com.example", ArtifactID: "core"},
+		&PythonPackage{Dir: "py"},
+	}
+
+	g := DependencyGraph(units)
+	var got []string
+	for _, e := range g.Edges {
+		got = append(got, fmt.Sprintf("%s -> %s (%s)", e.From.Path(), e.To.Path(), e.Dependency.Scope))
+	}
+	want := []string{
+		"cmd/x -> lib (runtime)",
+		"web -> testkit (dev)",
+		"web -> ui (runtime)",
+		"app -> core (runtime)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got edges %q, want %q", got, want)
+	}
+
+	if deps := g.DependenciesOf(units[2]); len(deps) != 2 {
+		t.Errorf("got %d dependencies of web, want 2", len(deps))
+	}
+	if deps := g.DependentsOf(units[3]); len(deps) != 1 || deps[0].From != units[2] {
+		t.Errorf("got dependents of ui %v, want web", deps)
+	}
+
+	var dot bytes.Buffer
+	if err := g.WriteDOT(&dot); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`u1 [label="GoPackage\nlib"];`, "u0 -> u1;", `u2 -> u4 [label="dev"];`} {
+		if !bytes.Contains(dot.Bytes(), []byte(s)) {
+			t.Errorf("DOT output does not contain %q:\n%s", s, dot.String())
+		}
+	}
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		Units []*MarshalableUnit
+		Edges []struct{ From, To int }
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Units) != len(units) || !reflect.DeepEqual(v.Units[7].Unit, units[7]) {
+		t.Errorf("got JSON units %v, want %v", v.Units, units)
+	}
+	if len(v.Edges) != 4 || v.Edges[3].From != 6 || v.Edges[3].To != 7 {
+		t.Errorf("got JSON edges %+v", v.Edges)
+	}
+}
+
+func TestDependencyGraph_GoModule(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.FileSystem = MapFS(map[string]string{
+		"go.mod":        "module example.com/r // comment\n\ngo 1.16\n",
+		"cmd/x/main.go": "package main\n\nimport \"example.com/r/lib\"\n",
+		"lib/lib.go":    "package lib\n",
+		"other/go.mod":  "module \"example.com/other\"\n",
+		"other/y/y.go":  "package y\n\nimport \"example.com/r/lib\"\n",
+	})
+	units, err := config.Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var importPaths []string
+	for _, unit := range units {
+		importPaths = append(importPaths, unit.(*GoPackage).ImportPath)
+	}
+	if want := []string{"example.com/r/cmd/x", "example.com/r/lib", "example.com/other/y"}; !reflect.DeepEqual(importPaths, want) {
+		t.Errorf("got import paths %q, want %q", importPaths, want)
+	}
+
+	var got []string
+	for _, e := range DependencyGraph(units).Edges {
+		got = append(got, fmt.Sprintf("%s -> %s (%s)", e.From.Path(), e.To.Path(), e.Dependency.Scope))
+	}
+	if want := []string{"cmd/x -> lib (runtime)", "other/y -> lib (runtime)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got edges %q, want %q", got, want)
+	}
+}
+
+// fileListUnit is a source unit whose type is not comparable.
+type fileListUnit []string
+
+func (u fileListUnit) Path() string { return u[0] }
+
+func TestDependencyGraph_UncomparableUnits(t *testing.T) {
+	units := []Unit{
+		fileListUnit{"a"},
+		&RubyGem{Dir: "x", Name: "x", Deps: []Dependency{{Name: "y", Scope: ScopeRuntime, Ecosystem: "rubygems"}}},
+		fileListUnit{"b"},
+		&RubyGem{Dir: "y", Name: "y"},
+	}
+	g := DependencyGraph(units)
+	var buf bytes.Buffer
+	if err := g.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("u1 -> u3;")) {
+		t.Errorf("got DOT output %q, want edge u1 -> u3", buf.String())
+	}
+	if deps := g.DependenciesOf(units[0]); len(deps) != 0 {
+		t.Errorf("got dependencies %+v of %v, want none", deps, units[0])
+	}
+	if deps := g.DependentsOf(units[3]); len(deps) != 1 || deps[0].From != units[1] {
+		t.Errorf("got dependents %+v of %v, want x", deps, units[3])
+	}
+}
@@ -0,0 +1,120 @@
+package srcscan
+
+import (
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// An ID identifies a source unit across scans and repositories. Its Ecosystem and Name are those
+// in the unit's Metadata, so that a unit keeps its ID when it moves to another directory; Version
+// and Path qualify it.
+type ID struct {
+	Ecosystem string // package ecosystem, or "generic" if it is unknown
+	Name      string
+	Version   string `json:",omitempty"`
+	Path      string `json:",omitempty"` // slash-separated Unit.Path, which is relative to Config.Base
+
+	// Declared is whether the unit declares its Name (in a manifest, or as the import path of a
+	// GoPackage), rather than the Name being the base name of its path.
+	Declared bool `json:",omitempty"`
+}
+
+// UnitID returns the ID of unit.
+func UnitID(unit Unit) ID {
+	md := UnitMetadata(unit)
+	id := ID{Ecosystem: md.Ecosystem, Name: md.Name, Version: md.Version, Path: filepath.ToSlash(unit.Path())}
+	if id.Ecosystem == "" {
+		id.Ecosystem = "generic"
+	}
+	switch u := unit.(type) {
+	case *NPMPackage, *BowerComponent, *GoPackage, *JavaProject:
+		key, _ := unitKey(u)
+		id.Declared = key.Name != ""
+	case *RubyGem:
+		id.Declared = u.Name != ""
+	}
+	return id
+}
+
+// purlTypes maps ecosystems to package URL types, where they differ.
+var purlTypes = map[string]string{
+	"go":       "golang",
+	"rubygems": "gem",
+}
+
+// Key returns the ID without its Version and Path, as a package URL (such as "pkg:npm/mypkg").
+// Units with the same Key are different versions or copies of the same package. Key returns "" if
+// the Name is not Declared, because units whose names are the base names of their paths (such as
+// two Python packages in directories named "lib") are not necessarily the same package.
+func (id ID) Key() string {
+	if !id.Declared {
+		return ""
+	}
+	return id.purl()
+}
+
+// purl returns the ID without its Version and Path, as a package URL.
+func (id ID) purl() string {
+	typ := id.Ecosystem
+	if t, ok := purlTypes[typ]; ok {
+		typ = t
+	}
+	name := id.Name
+	if id.Ecosystem == "maven" {
+		name = strings.Replace(name, ":", "/", 1) // "groupId:artifactId" is "groupId/artifactId"
+	}
+	return "pkg:" + typ + "/" + escapePath(name)
+}
+
+// String returns the ID as a package URL, with the Path as its subpath (such as
+// "pkg:npm/mypkg@1.2.3#lib/mypkg"). The Path is omitted if it is ".".
+func (id ID) String() string {
+	s := id.purl()
+	if id.Version != "" {
+		s += "@" + url.PathEscape(id.Version)
+	}
+	if id.Path != "" && id.Path != "." {
+		s += "#" + escapePath(id.Path)
+	}
+	return s
+}
+
+// escapePath percent-encodes each component of the slash-separated path p for use in a package
+// URL.
+func escapePath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		parts[i] = strings.Replace(url.PathEscape(part), "@", "%40", -1)
+	}
+	return strings.Join(parts, "/")
+}
+
+// SortByID sorts units by ID (see UnitID), and then by type. The ID of each unit is computed once.
+func SortByID(units []Unit) {
+	b := unitsByID{units: units, ids: make([]string, len(units)), types: make([]string, len(units))}
+	for i, unit := range units {
+		b.ids[i], b.types[i] = UnitID(unit).String(), UnitType(unit)
+	}
+	sort.Sort(b)
+}
+
+// unitsByID sorts units by their precomputed IDs and types.
+type unitsByID struct {
+	units      []Unit
+	ids, types []string
+}
+
+func (b unitsByID) Len() int { return len(b.units) }
+func (b unitsByID) Swap(i, j int) {
+	b.units[i], b.units[j] = b.units[j], b.units[i]
+	b.ids[i], b.ids[j] = b.ids[j], b.ids[i]
+	b.types[i], b.types[j] = b.types[j], b.types[i]
+}
+func (b unitsByID) Less(i, j int) bool {
+	if b.ids[i] != b.ids[j] {
+		return b.ids[i] < b.ids[j]
+	}
+	return b.types[i] < b.types[j]
+}
@@ -0,0 +1,88 @@
+package srcscan
+
+import (
+	"go/build"
+	"reflect"
+	"testing"
+)
+
+func TestUnitID(t *testing.T) {
+	tests := []struct {
+		unit      Unit
+		key, want string
+	}{
+		{
+			&NPMPackage{Dir: "lib/ui", PackageJSON: []byte(`{"name":"@acme/ui","version":"1.2.3"}`)},
+			"pkg:npm/%40acme/ui",
+			"pkg:npm/%40acme/ui@1.2.3#lib/ui",
+		},
+		{
+			&NPMPackage{Dir: "moved/ui", PackageJSON: []byte(`{"name":"@acme/ui","version":"1.2.3"}`)},
+			"pkg:npm/%40acme/ui",
+			"pkg:npm/%40acme/ui@1.2.3#moved/ui",
+		},
+		{
+			&NPMPackage{Dir: ".", PackageJSON: []byte(`{"name":"root"}`)},
+			"pkg:npm/root",
+			"pkg:npm/root",
+		},
+		{
+			&NPMPackage{Dir: "unnamed", PackageJSON: []byte(`{}`)},
+			"",
+			"pkg:npm/unnamed#unnamed",
+		},
+		{
+			&GoPackage{Package: build.Package{Dir: "x", Name: "x", ImportPath: "example.com/r/x"}},
+			"pkg:golang/example.com/r/x",
+			"pkg:golang/example.com/r/x#x",
+		},
+		{
+			&GoPackage{Package: build.Package{Dir: "cmd/x", Name: "main", ImportPath: "."}},
+			"",
+			"pkg:golang/main#cmd/x",
+		},
+		{
+			&JavaProject{Dir: "core", GroupID: "com.example", ArtifactID: "core", Version: "1.0"},
+			"pkg:maven/com.example/core",
+			"pkg:maven/com.example/core@1.0#core",
+		},
+		{
+			&RubyGem{Dir: "gems/g", Name: "g"},
+			"pkg:gem/g",
+			"pkg:gem/g#gems/g",
+		},
+		{
+			&PythonPackage{Dir: "py/lib"},
+			"",
+			"pkg:pypi/lib#py/lib",
+		},
+		{
+			&GenericUnit{Profile: "Bazel package", Dir: "cc/lib"},
+			"",
+			"pkg:generic/lib#cc/lib",
+		},
+	}
+	for _, test := range tests {
+		id := UnitID(test.unit)
+		if key := id.Key(); key != test.key {
+			t.Errorf("%T %s: got Key %q, want %q", test.unit, test.unit.Path(), key, test.key)
+		}
+		if s := id.String(); s != test.want {
+			t.Errorf("%T %s: got ID %q, want %q", test.unit, test.unit.Path(), s, test.want)
+		}
+	}
+}
+
+func TestSortByID(t *testing.T) {
+	units := []Unit{
+		&RubyGem{Dir: "a", Name: "z"},
+		&NPMPackage{Dir: "b", PackageJSON: []byte(`{"name":"y"}`)},
+		&BowerComponent{Dir: "c", BowerJSON: []byte(`{"name":"y"}`)},
+		&NPMPackage{Dir: "a", PackageJSON: []byte(`{"name":"y"}`)},
+	}
+	want := []Unit{units[2], units[0], units[3], units[1]}
+	SortByID(units)
+	if !reflect.DeepEqual(units, want) {
+		t.Errorf("got units %v, want %v", units, want)
+	}
+}
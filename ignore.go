@@ -0,0 +1,272 @@
+package srcscan
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// An ignorePattern is a pattern from a .gitignore (or .git/info/exclude) file.
+type ignorePattern struct {
+	base    string // ignorer key of the directory that the pattern is relative to
+	re      *regexp.Regexp
+	negate  bool // the pattern began with "!"
+	dirOnly bool // the pattern ended with "/"
+}
+
+// parseIgnoreFile parses the patterns in the contents of an ignore file that apply relative to
+// the directory base, following the rules described in gitignore(5).
+func parseIgnoreFile(base string, data []byte) (pats []ignorePattern) {
+	for _, line := range strings.Split(string(data), "\n") {
+		if pat, ok := parseIgnorePattern(line); ok {
+			pat.base = base
+			pats = append(pats, pat)
+		}
+	}
+	return
+}
+
+func parseIgnorePattern(line string) (pat ignorePattern, ok bool) {
+	line = strings.TrimSuffix(line, "\r")
+
+	// Trailing spaces are ignored unless they are escaped.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || line[0] == '#' {
+		return pat, false
+	}
+
+	if line[0] == '!' {
+		pat.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		pat.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// A pattern with a slash at the beginning or in the middle is relative to the directory of
+	// the ignore file; otherwise, it matches at any level below it.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return pat, false
+	}
+
+	expr := globRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return pat, false
+	}
+	pat.re = re
+	return pat, true
+}
+
+// globRegexp translates a gitignore glob to a regular expression. As in gitignore(5), "*", "?"
+// and character classes do not match "/", and "**" matches across directories when it is a whole
+// path component.
+func globRegexp(glob string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			buf.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**" && (i == 0 || glob[i-1] == '/'):
+			buf.WriteString(".*")
+			i++
+		case c == '*':
+			buf.WriteString("[^/]*")
+		case c == '?':
+			buf.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == -1 {
+				buf.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			buf.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			buf.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return buf.String()
+}
+
+// ignoreRules is the list of patterns that apply in a directory, in increasing order of
+// precedence.
+type ignoreRules []ignorePattern
+
+// ignored reports whether the file or directory with the given ignorer key is ignored.
+func (r ignoreRules) ignored(key string, isDir bool) bool {
+	for i := len(r) - 1; i >= 0; i-- {
+		pat := r[i]
+		if pat.dirOnly && !isDir {
+			continue
+		}
+		rel, ok := keyRel(pat.base, key)
+		if ok && pat.re.MatchString(rel) {
+			return !pat.negate
+		}
+	}
+	return false
+}
+
+// keyRel returns the slash-separated path of key relative to base, if key is below base.
+func keyRel(base, key string) (string, bool) {
+	if base == "." {
+		return filepath.ToSlash(key), key != "."
+	}
+	if !strings.HasSuffix(base, string(filepath.Separator)) {
+		base += string(filepath.Separator)
+	}
+	if !strings.HasPrefix(key, base) || key == base {
+		return "", false
+	}
+	return filepath.ToSlash(key[len(base):]), true
+}
+
+// An ignorer determines which paths in a scan are ignored by .gitignore files (in the scanned
+// directory, its subdirectories and, on the OS file system, its ancestors up to the root of the
+// enclosing git repository) and the repository's .git/info/exclude file. The .git directory
+// itself is always ignored. Ignore files are read as they are needed.
+//
+// Paths are compared by key: their absolute path on the OS file system, or their cleaned path on
+// other file systems.
+type ignorer struct {
+	fs  FileSystem
+	abs bool   // whether keys are absolute paths
+	top string // key of the root of the repository (or of the scanned directory)
+
+	mu    sync.Mutex
+	rules map[string]ignoreRules // directory key -> rules that apply in it
+}
+
+func newIgnorer(fs FileSystem, abs bool, dir string) *ignorer {
+	ig := &ignorer{fs: fs, abs: abs, rules: make(map[string]ignoreRules)}
+	ig.top = ig.key(dir)
+	if abs {
+		for d := ig.top; ; d = filepath.Dir(d) {
+			if isDir(fs, filepath.Join(d, ".git")) {
+				ig.top = d
+				break
+			}
+			if filepath.Dir(d) == d {
+				break
+			}
+		}
+	}
+	return ig
+}
+
+func (ig *ignorer) key(path string) string {
+	return pathKey(ig.abs, path)
+}
+
+// pathKey returns the absolute path of path if abs is set, or its cleaned path otherwise.
+func pathKey(abs bool, path string) string {
+	if abs {
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs
+		}
+	}
+	return filepath.Clean(path)
+}
+
+// ignored reports whether the file or directory at path is ignored.
+func (ig *ignorer) ignored(path string, isDir bool) bool {
+	if filepath.Base(path) == ".git" {
+		return true
+	}
+	key := ig.key(path)
+	if _, below := keyRel(ig.top, key); !below {
+		return false
+	}
+	return ig.rulesFor(filepath.Dir(key)).ignored(key, isDir)
+}
+
+// rulesFor returns the rules that apply in the directory with the given key, which must be ig.top
+// or below it.
+func (ig *ignorer) rulesFor(dir string) ignoreRules {
+	ig.mu.Lock()
+	rules, loaded := ig.rules[dir]
+	ig.mu.Unlock()
+	if loaded {
+		return rules
+	}
+
+	if dir == ig.top {
+		if data, err := readFile(ig.fs, filepath.Join(dir, ".git", "info", "exclude")); err == nil {
+			rules = append(rules, parseIgnoreFile(dir, data)...)
+		}
+	} else {
+		rules = append(rules, ig.rulesFor(filepath.Dir(dir))...)
+	}
+	if data, err := readFile(ig.fs, filepath.Join(dir, ".gitignore")); err == nil {
+		rules = append(rules, parseIgnoreFile(dir, data)...)
+	}
+
+	ig.mu.Lock()
+	ig.rules[dir] = rules
+	ig.mu.Unlock()
+	return rules
+}
+
+// ignoringFS is a FileSystem from which the paths ignored by an ignorer are omitted.
+type ignoringFS struct {
+	FileSystem
+	ig *ignorer
+}
+
+func (fs ignoringFS) Lstat(path string) (os.FileInfo, error) {
+	fi, err := fs.FileSystem.Lstat(path)
+	if err == nil && fs.ig.ignored(path, fi.IsDir()) {
+		return nil, &os.PathError{Op: "lstat", Path: path, Err: os.ErrNotExist}
+	}
+	return fi, err
+}
+
+func (fs ignoringFS) Stat(path string) (os.FileInfo, error) {
+	if _, err := fs.Lstat(path); err != nil {
+		return nil, err
+	}
+	return fs.FileSystem.Stat(path)
+}
+
+func (fs ignoringFS) Open(path string) (io.ReadCloser, error) {
+	if _, err := fs.Lstat(path); err != nil {
+		return nil, err
+	}
+	return fs.FileSystem.Open(path)
+}
+
+func (fs ignoringFS) ReadDir(path string) ([]os.FileInfo, error) {
+	infos, err := fs.FileSystem.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	kept := infos[:0]
+	for _, fi := range infos {
+		if !fs.ig.ignored(filepath.Join(path, fi.Name()), fi.IsDir()) {
+			kept = append(kept, fi)
+		}
+	}
+	return kept, nil
+}
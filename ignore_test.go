@@ -0,0 +1,87 @@
+package srcscan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"*.min.js", "a.min.js", false, true},
+		{"*.min.js", "lib/a.min.js", false, true},
+		{"*.min.js", "lib/a.js", false, false},
+		{"build/", "build", true, true},
+		{"build/", "web/build", true, true},
+		{"build/", "build", false, false},
+		{"/build", "build", true, true},
+		{"/build", "web/build", true, false},
+		{"web/build", "web/build", true, true},
+		{"web/build", "x/web/build", true, false},
+		{"**/cache", "a/b/cache", true, true},
+		{"**/cache", "cache", true, true},
+		{"a/**/b", "a/b", true, true},
+		{"a/**/b", "a/x/y/b", true, true},
+		{"out/**", "out/a/b.js", false, true},
+		{"out/**", "out", true, false},
+		{"?.py", "a.py", false, true},
+		{"?.py", "ab.py", false, false},
+		{"[ab].go", "a.go", false, true},
+		{"[!ab].go", "a.go", false, false},
+		{"[!ab].go", "c.go", false, true},
+		{`\#foo`, "#foo", false, true},
+		{"# comment", "# comment", false, false},
+		{"foo  ", "foo", false, true},
+	}
+	for _, test := range tests {
+		rules := ignoreRules(parseIgnoreFile(".", []byte(test.pattern)))
+		if ignored := rules.ignored(test.path, test.isDir); ignored != test.ignored {
+			t.Errorf("pattern %q, path %q (dir %v): got ignored %v, want %v", test.pattern, test.path, test.isDir, ignored, test.ignored)
+		}
+	}
+}
+
+func TestScan_RespectGitIgnore(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.RespectGitIgnore = true
+	config.Profiles = []Profile{AllProfiles[0], AllProfiles[2]}
+	config.FileSystem = MapFS(map[string]string{
+		".git/info/exclude":        "/tmp\n",
+		".gitignore":               "*.min.js\nbuild/\n!keep.min.js\n",
+		"npm/.gitignore":           "/lib/gen.js\n",
+		"npm/package.json":         `{}`,
+		"npm/a.min.js":             "",
+		"npm/keep.min.js":          "",
+		"npm/lib/a.js":             "",
+		"npm/lib/gen.js":           "",
+		"npm/build/b.js":           "",
+		"npm/sub/lib/gen.js":       "",
+		"build/package.json":       `{}`,
+		"tmp/x.py":                 "",
+		"python/tmp/x.py":          "",
+		"python/mypkg/__init__.py": "",
+	})
+
+	units, err := config.Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Unit{
+		&NPMPackage{
+			Dir:            "npm",
+			PackageJSON:    []byte(`{}`),
+			LibFiles:       []string{"lib/a.js", "sub/lib/gen.js"},
+			GeneratedFiles: []string{"keep.min.js"},
+		},
+		&PythonPackage{Dir: "python/mypkg"},
+		&PythonModule{File: "python/tmp/x.py"},
+	}
+	if !reflect.DeepEqual(want, units) {
+		t.Errorf("got units %+v, want %+v", units, want)
+	}
+}
@@ -0,0 +1,58 @@
+package srcscan
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+// Errors recorded in Result.Truncated.
+var (
+	ErrMaxDepth        = errors.New("directory is deeper than Config.MaxDepth")
+	ErrMaxEntries      = errors.New("directory was not fully scanned because Config.MaxEntries was reached")
+	ErrMaxFilesPerUnit = errors.New("source unit has more files than Config.MaxFilesPerUnit")
+	ErrMaxManifestSize = errors.New("manifest is larger than Config.MaxManifestSize")
+)
+
+// errStopWalk is returned by a walk function to end the walk without error.
+var errStopWalk = errors.New("stop walk")
+
+// truncated reports that the source unit being read was truncated because of the limit described
+// by err.
+func (c Config) truncated(err error) {
+	if c.truncate != nil {
+		c.truncate(err)
+	}
+}
+
+// A fileLimiter counts the files listed in a source unit, enforcing Config.MaxFilesPerUnit.
+type fileLimiter struct {
+	config Config
+	n      int
+}
+
+// add reports whether another file may be listed in the source unit. It reports the truncation
+// of the unit the first time that the limit is exceeded.
+func (l *fileLimiter) add() bool {
+	l.n++
+	if max := l.config.MaxFilesPerUnit; max > 0 && l.n > max {
+		if l.n == max+1 {
+			l.config.truncated(ErrMaxFilesPerUnit)
+		}
+		return false
+	}
+	return true
+}
+
+// readManifest reads the manifest file named filename in dir. If the file is larger than
+// c.MaxManifestSize, readManifest reports the truncation and returns nil.
+func readManifest(c Config, dir, filename string) ([]byte, error) {
+	data, truncated, err := readFileLimit(c.fs(), filepath.Join(dir, filename), c.MaxManifestSize)
+	if err != nil {
+		return nil, err
+	}
+	if truncated {
+		c.truncated(ErrMaxManifestSize)
+		return nil, nil
+	}
+	return data, nil
+}
@@ -0,0 +1,110 @@
+package srcscan
+
+import (
+	"github.com/kr/pretty"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScan_Limits(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.PathIndependent = true
+	config.MaxDepth = 2
+	config.MaxFilesPerUnit = 2
+	config.MaxManifestSize = 10
+	config.FileSystem = MapFS(map[string]string{
+		"a/b/package.json":   `{}`,
+		"a/b/c/package.json": `{}`,
+		"big/package.json":   `{"name":"big"}`,
+		"npm/package.json":   `{}`,
+		"npm/a.js":           "",
+		"npm/b.js":           "",
+		"npm/c.js":           "",
+	})
+
+	r, err := config.ScanResult(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantUnits := []Unit{
+		&NPMPackage{Dir: "a/b", PackageJSON: []byte(`{}`)},
+		&NPMPackage{Dir: "big"},
+		&NPMPackage{Dir: "npm", PackageJSON: []byte(`{}`), LibFiles: []string{"a.js", "b.js"}},
+	}
+	if !reflect.DeepEqual(wantUnits, r.Units) {
+		t.Errorf("units:\n%v", strings.Join(pretty.Diff(wantUnits, r.Units), "\n"))
+	}
+	wantTruncated := ScanErrors{
+		{Path: "a/b/c", Err: ErrMaxDepth},
+		{Path: "big", Profile: "NPM package", Err: ErrMaxManifestSize},
+		{Path: "npm", Profile: "NPM package", Err: ErrMaxFilesPerUnit},
+	}
+	if !reflect.DeepEqual(wantTruncated, r.Truncated) {
+		t.Errorf("got Truncated %v, want %v", r.Truncated, wantTruncated)
+	}
+
+	// Scan must not ignore the truncations.
+	units, err := config.Scan(".")
+	if len(units) != len(wantUnits) {
+		t.Errorf("got %d units, want %d", len(units), len(wantUnits))
+	}
+	if !reflect.DeepEqual(err, wantTruncated) {
+		t.Errorf("got error %v, want %v", err, wantTruncated)
+	}
+}
+
+func TestScan_MaxEntries(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.PathIndependent = true
+	config.MaxEntries = 3
+	config.FileSystem = MapFS(map[string]string{
+		"a/package.json": `{}`,
+		"b/package.json": `{}`,
+		"c/package.json": `{}`,
+	})
+
+	r, err := config.ScanResult(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The entries a, a/package.json and b are visited. The listing of b is read, so it is matched,
+	// but its entries are not visited.
+	want := []Unit{
+		&NPMPackage{Dir: "a", PackageJSON: []byte(`{}`)},
+		&NPMPackage{Dir: "b", PackageJSON: []byte(`{}`)},
+	}
+	if !reflect.DeepEqual(want, r.Units) {
+		t.Errorf("units:\n%v", strings.Join(pretty.Diff(want, r.Units), "\n"))
+	}
+	wantTruncated := ScanErrors{{Path: ".", Err: ErrMaxEntries}, {Path: "b", Err: ErrMaxEntries}}
+	if !reflect.DeepEqual(wantTruncated, r.Truncated) {
+		t.Errorf("got Truncated %v, want %v", r.Truncated, wantTruncated)
+	}
+}
+
+func TestScan_MaxManifestSize_WrongStatSize(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.PathIndependent = true
+	config.MaxManifestSize = 10
+	// The file system reports a size within the limit (as an archive's declared size might), but
+	// the manifest is larger.
+	big := memTreeFile([]byte(`{"name":"big"}`), 0)
+	big.size = 2
+	config.FileSystem = newTreeFS(map[string]treeFile{"big/package.json": big})
+
+	r, err := config.ScanResult(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Unit{&NPMPackage{Dir: "big"}}; !reflect.DeepEqual(want, r.Units) {
+		t.Errorf("units:\n%v", strings.Join(pretty.Diff(want, r.Units), "\n"))
+	}
+	wantTruncated := ScanErrors{{Path: "big", Profile: "NPM package", Err: ErrMaxManifestSize}}
+	if !reflect.DeepEqual(wantTruncated, r.Truncated) {
+		t.Errorf("got Truncated %v, want %v", r.Truncated, wantTruncated)
+	}
+}
@@ -0,0 +1,258 @@
+package srcscan
+
+import (
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// A Matcher matches both directories and files. Matchers are combined with AllOf, AnyOf and Not.
+// A Matcher that is meant for directories (such as FileInDir) matches no files, and one that is
+// meant for files (such as FileHasSuffix) matches directories as it matches files, by their paths.
+type Matcher interface {
+	DirMatcher
+	FileMatcher
+}
+
+// FSDirMatcher is implemented by DirMatchers that read the file system to match a directory.
+// During a scan, DirMatchesFS is called with the FileSystem being scanned instead of DirMatches,
+// which reads the operating system's file system.
+type FSDirMatcher interface {
+	DirMatcher
+	DirMatchesFS(fs FileSystem, path string, filenames []string) bool
+}
+
+// FSFileMatcher is implemented by FileMatchers that read the file system to match a file. During
+// a scan, FileMatchesFS is called with the FileSystem being scanned instead of FileMatches, which
+// reads the operating system's file system.
+type FSFileMatcher interface {
+	FileMatcher
+	FileMatchesFS(fs FileSystem, path string) bool
+}
+
+// dirMatches reports whether m matches the directory at path in fs, whose entries are named
+// filenames.
+func dirMatches(fs FileSystem, m DirMatcher, path string, filenames []string) bool {
+	if m, ok := m.(FSDirMatcher); ok {
+		return m.DirMatchesFS(fs, path, filenames)
+	}
+	return m.DirMatches(path, filenames)
+}
+
+// fileMatches reports whether m matches the file at path in fs.
+func fileMatches(fs FileSystem, m FileMatcher, path string) bool {
+	if m, ok := m.(FSFileMatcher); ok {
+		return m.FileMatchesFS(fs, path)
+	}
+	return m.FileMatches(path)
+}
+
+// AllOf matches directories and files that all of its elements match.
+type AllOf []Matcher
+
+func (c AllOf) DirMatches(path string, filenames []string) bool {
+	return c.DirMatchesFS(OS, path, filenames)
+}
+
+func (c AllOf) DirMatchesFS(fs FileSystem, path string, filenames []string) bool {
+	for _, m := range c {
+		if !dirMatches(fs, m, path, filenames) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c AllOf) FileMatches(path string) bool { return c.FileMatchesFS(OS, path) }
+
+func (c AllOf) FileMatchesFS(fs FileSystem, path string) bool {
+	for _, m := range c {
+		if !fileMatches(fs, m, path) {
+			return false
+		}
+	}
+	return true
+}
+
+// AnyOf matches directories and files that any of its elements matches.
+type AnyOf []Matcher
+
+func (c AnyOf) DirMatches(path string, filenames []string) bool {
+	return c.DirMatchesFS(OS, path, filenames)
+}
+
+func (c AnyOf) DirMatchesFS(fs FileSystem, path string, filenames []string) bool {
+	for _, m := range c {
+		if dirMatches(fs, m, path, filenames) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c AnyOf) FileMatches(path string) bool { return c.FileMatchesFS(OS, path) }
+
+func (c AnyOf) FileMatchesFS(fs FileSystem, path string) bool {
+	for _, m := range c {
+		if fileMatches(fs, m, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Not matches directories and files that its Matcher doesn't match.
+type Not struct{ Matcher Matcher }
+
+func (c Not) DirMatches(path string, filenames []string) bool {
+	return c.DirMatchesFS(OS, path, filenames)
+}
+
+func (c Not) DirMatchesFS(fs FileSystem, path string, filenames []string) bool {
+	return !dirMatches(fs, c.Matcher, path, filenames)
+}
+
+func (c Not) FileMatches(path string) bool { return c.FileMatchesFS(OS, path) }
+
+func (c Not) FileMatchesFS(fs FileSystem, path string) bool {
+	return !fileMatches(fs, c.Matcher, path)
+}
+
+// NameGlob matches files whose names match the shell pattern Pattern (using filepath.Match), and
+// directories containing such a file.
+type NameGlob struct{ Pattern string }
+
+func (c NameGlob) DirMatches(path string, filenames []string) bool {
+	for _, f := range filenames {
+		if c.FileMatches(f) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c NameGlob) FileMatches(path string) bool {
+	match, _ := filepath.Match(c.Pattern, filepath.Base(path))
+	return match
+}
+
+// NameRegexp matches files whose names match Regexp, and directories containing such a file.
+type NameRegexp struct{ Regexp *regexp.Regexp }
+
+func (c NameRegexp) DirMatches(path string, filenames []string) bool {
+	for _, f := range filenames {
+		if c.Regexp.MatchString(f) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c NameRegexp) FileMatches(path string) bool {
+	return c.Regexp.MatchString(filepath.Base(path))
+}
+
+// PathGlob matches directories and files whose slash-separated paths, relative to the scanned
+// directory, end with path components matching Pattern. The syntax of Pattern is that of
+// gitignore(5): "*", "?" and character classes match within a path component, and "**" matches
+// any number of components. For example, "src/**/*.java" matches "a/src/main/Foo.java". Outside
+// of a scan, DirMatches and FileMatches match the path they are given.
+type PathGlob struct{ Pattern string }
+
+func (c PathGlob) DirMatches(path string, filenames []string) bool { return c.FileMatches(path) }
+
+func (c PathGlob) DirMatchesFS(fs FileSystem, path string, filenames []string) bool {
+	return c.FileMatchesFS(fs, path)
+}
+
+func (c PathGlob) FileMatches(path string) bool {
+	re := pathGlobRegexp(c.Pattern)
+	return re != nil && re.MatchString(filepath.ToSlash(path))
+}
+
+func (c PathGlob) FileMatchesFS(fs FileSystem, path string) bool {
+	if fs, ok := fs.(scanFS); ok {
+		rel, err := fs.rel(path)
+		if err != nil {
+			return false
+		}
+		path = rel
+	}
+	return c.FileMatches(path)
+}
+
+var pathGlobs = struct {
+	sync.Mutex
+	m map[string]*regexp.Regexp
+}{m: make(map[string]*regexp.Regexp)}
+
+// pathGlobRegexp returns the compiled regular expression for a PathGlob pattern, or nil if it is
+// invalid.
+func pathGlobRegexp(pattern string) *regexp.Regexp {
+	pathGlobs.Lock()
+	defer pathGlobs.Unlock()
+	re, ok := pathGlobs.m[pattern]
+	if !ok {
+		re, _ = regexp.Compile("(?:^|/)" + globRegexp(pattern) + "$")
+		pathGlobs.m[pattern] = re
+	}
+	return re
+}
+
+// PathInDir matches directories that contain the file or directory at the slash-separated relative
+// path Path, such as "src/main/java".
+type PathInDir struct{ Path string }
+
+func (c PathInDir) DirMatches(path string, filenames []string) bool {
+	return c.DirMatchesFS(OS, path, filenames)
+}
+
+func (c PathInDir) DirMatchesFS(fs FileSystem, path string, filenames []string) bool {
+	_, err := fs.Stat(filepath.Join(path, filepath.FromSlash(c.Path)))
+	return err == nil
+}
+
+// FileMatches returns false, because PathInDir only matches directories.
+func (c PathInDir) FileMatches(path string) bool { return false }
+
+// FileContains matches files whose names match the shell pattern Name (or any file, if Name is
+// empty) and whose contents match Pattern, and directories containing such a file. Only regular
+// files of at most 1 MiB are matched, so that matching doesn't read large files (such as
+// binaries or data) into memory.
+type FileContains struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+func (c FileContains) DirMatches(path string, filenames []string) bool {
+	return c.DirMatchesFS(OS, path, filenames)
+}
+
+func (c FileContains) DirMatchesFS(fs FileSystem, path string, filenames []string) bool {
+	for _, f := range filenames {
+		if c.FileMatchesFS(fs, filepath.Join(path, f)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c FileContains) FileMatches(path string) bool { return c.FileMatchesFS(OS, path) }
+
+func (c FileContains) FileMatchesFS(fs FileSystem, path string) bool {
+	if c.Name != "" {
+		if match, _ := filepath.Match(c.Name, filepath.Base(path)); !match {
+			return false
+		}
+	}
+	if fi, err := fs.Stat(path); err != nil || !fi.Mode().IsRegular() || fi.Size() > maxFileContainsSize {
+		return false
+	}
+	data, truncated, err := readFileLimit(fs, path, maxFileContainsSize)
+	return err == nil && !truncated && c.Pattern.Match(data)
+}
+
+// maxFileContainsSize is the size in bytes of the largest file that FileContains matches.
+const maxFileContainsSize = 1 << 20
+
+var _, _, _, _, _, _, _, _, _, _, _ Matcher = AllOf{}, AnyOf{}, Not{}, NameGlob{}, NameRegexp{}, PathGlob{}, PathInDir{}, FileContains{}, FileInDir{}, FileSuffixInDir{}, FileHasSuffix{}
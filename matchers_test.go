@@ -0,0 +1,139 @@
+package srcscan
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMatchers(t *testing.T) {
+	fs := MapFS(map[string]string{
+		"proj/pom.xml":                   "<project/>",
+		"proj/src/main/java/Foo.java":    "",
+		"proj/setup.py":                  "from setuptools import setup\n",
+		"proj/test/Bar.java":             "",
+		"other/setup.py":                 "from distutils.core import setup\n",
+		"other/src/main/scala/Foo.scala": "",
+	})
+	filenames := func(dir string) (names []string) {
+		infos, err := fs.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, fi := range infos {
+			names = append(names, fi.Name())
+		}
+		return
+	}
+
+	dirTests := []struct {
+		m    DirMatcher
+		dir  string
+		want bool
+	}{
+		{PathInDir{"src/main/java"}, "proj", true},
+		{PathInDir{"src/main/java"}, "other", false},
+		{FileContains{"setup.py", regexp.MustCompile(`setuptools`)}, "proj", true},
+		{FileContains{"setup.py", regexp.MustCompile(`setuptools`)}, "other", false},
+		{NameGlob{"*.xml"}, "proj", true},
+		{NameRegexp{regexp.MustCompile(`^pom\.xml$`)}, "other", false},
+		{AllOf{FileInDir{"pom.xml"}, PathInDir{"src/main/java"}}, "proj", true},
+		{AllOf{FileInDir{"setup.py"}, Not{PathInDir{"src/main/java"}}}, "proj", false},
+		{AllOf{FileInDir{"setup.py"}, Not{PathInDir{"src/main/java"}}}, "other", true},
+		{AnyOf{FileInDir{"pom.xml"}, FileInDir{"build.gradle"}}, "other", false},
+		{AllOf{FileInDir{"setup.py"}, Not{PathGlob{"oth*"}}}, "other", false},
+	}
+	for i, test := range dirTests {
+		if got := dirMatches(fs, test.m, test.dir, filenames(test.dir)); got != test.want {
+			t.Errorf("%d: %#v on dir %s: got %v, want %v", i, test.m, test.dir, got, test.want)
+		}
+	}
+
+	fileTests := []struct {
+		m    FileMatcher
+		path string
+		want bool
+	}{
+		{PathGlob{"src/**/*.java"}, "proj/src/main/java/Foo.java", true},
+		{PathGlob{"src/**/*.java"}, "proj/test/Bar.java", false},
+		{PathGlob{"test/*.java"}, "proj/test/Bar.java", true},
+		{NameGlob{"*.scala"}, "other/src/main/scala/Foo.scala", true},
+		{FileContains{"", regexp.MustCompile(`distutils`)}, "other/setup.py", true},
+		{AllOf{FileHasSuffix{".java"}, Not{PathGlob{"test/**"}}}, "proj/test/Bar.java", false},
+		{AnyOf{FileHasSuffix{".scala"}, FileHasSuffix{".java"}}, "proj/test/Bar.java", true},
+	}
+	for i, test := range fileTests {
+		if got := fileMatches(fs, test.m, test.path); got != test.want {
+			t.Errorf("%d: %#v on file %s: got %v, want %v", i, test.m, test.path, got, test.want)
+		}
+	}
+}
+
+func TestFileContains_Limits(t *testing.T) {
+	big := []byte(strings.Repeat(" ", maxFileContainsSize) + "setuptools")
+	misreported := memTreeFile(big, 0)
+	misreported.size = 1 // as an archive's declared size might be
+	fs := newTreeFS(map[string]treeFile{
+		"small/setup.py":       memTreeFile([]byte("setuptools"), 0),
+		"big/setup.py":         memTreeFile(big, 0),
+		"misreported/setup.py": misreported,
+		"pipe/setup.py":        memTreeFile([]byte("setuptools"), os.ModeNamedPipe),
+	})
+	m := FileContains{"", regexp.MustCompile(`setuptools`)}
+	for dir, want := range map[string]bool{"small": true, "big": false, "misreported": false, "pipe": false} {
+		if got := dirMatches(fs, m, dir, []string{"setup.py"}); got != want {
+			t.Errorf("%s: got %v, want %v", dir, got, want)
+		}
+	}
+}
+
+func TestScan_ComposedProfile(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.FileSystem = MapFS(map[string]string{
+		"a/setup.py": "from setuptools import setup\n",
+		"b/setup.py": "# nothing\n",
+	})
+	config.Profiles = []Profile{{
+		Name: "setuptools project",
+		Dir:  FileContains{"setup.py", regexp.MustCompile(`setuptools`)},
+		Unit: func(abspath, relpath string, config Config, info os.FileInfo) (Unit, error) {
+			return &PythonPackage{Dir: relpath}, nil
+		},
+	}}
+
+	units, err := config.Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Unit{&PythonPackage{Dir: "a"}}; !reflect.DeepEqual(units, want) {
+		t.Errorf("got units %v, want %v", units, want)
+	}
+}
+
+func TestScan_PathGlob(t *testing.T) {
+	config := Default
+	config.Base = "home/test/repo"
+	config.FileSystem = MapFS(map[string]string{
+		"home/test/repo/a.txt":      "",
+		"home/test/repo/test/b.txt": "",
+	})
+	config.Profiles = []Profile{{
+		Name: "test file",
+		File: PathGlob{"test/**"},
+		Unit: func(abspath, relpath string, config Config, info os.FileInfo) (Unit, error) {
+			return &PythonModule{File: relpath}, nil
+		},
+	}}
+
+	// The path of the scanned directory, which contains a "test" component, is not matched.
+	units, err := config.Scan("home/test/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Unit{&PythonModule{File: "test/b.txt"}}; !reflect.DeepEqual(units, want) {
+		t.Errorf("got units %v, want %v", units, want)
+	}
+}
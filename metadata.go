@@ -0,0 +1,180 @@
+package srcscan
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Metadata is information about a source unit that is available for all types of source units.
+type Metadata struct {
+	Name      string // name of the unit (such as the package name), or the base name of its path
+	Language  string // programming language (such as "JavaScript"), if known
+	Ecosystem string // package ecosystem (such as "npm"), if known
+	Version   string // version, if known (such as from package.json)
+
+	// Files lists the files of the unit, relative to its directory. It is empty for units that
+	// are a single file, such as PythonModule and RubyFile.
+	Files Files
+}
+
+// Files categorizes the files of a source unit.
+type Files struct {
+	Source    []string `json:",omitempty"`
+	Test      []string `json:",omitempty"`
+	Example   []string `json:",omitempty"`
+	Vendor    []string `json:",omitempty"`
+	Generated []string `json:",omitempty"`
+	Other     []string `json:",omitempty"` // files in no other category (such as scripts)
+}
+
+// A MetadataUnit is a Unit that describes itself with Metadata. All of the source unit types in
+// this package are MetadataUnits.
+type MetadataUnit interface {
+	Unit
+	Metadata() Metadata
+}
+
+// UnitMetadata returns the Metadata of unit. If unit is not a MetadataUnit, only the Name is set,
+// to the base name of its path.
+func UnitMetadata(unit Unit) Metadata {
+	if u, ok := unit.(MetadataUnit); ok {
+		return u.Metadata()
+	}
+	return Metadata{Name: filepath.Base(unit.Path())}
+}
+
+// manifestNameVersion returns the "name" and "version" properties of a JSON manifest, such as
+// package.json or bower.json.
+func manifestNameVersion(manifest []byte) (name, version string) {
+	var m struct{ Name, Version string }
+	json.Unmarshal(manifest, &m)
+	return m.Name, m.Version
+}
+
+func (u *NPMPackage) Metadata() Metadata {
+	md := Metadata{Language: "JavaScript", Ecosystem: "npm"}
+	md.Name, md.Version = manifestNameVersion(u.PackageJSON)
+	if md.Name == "" {
+		md.Name = filepath.Base(u.Dir)
+	}
+	md.Files = Files{
+		Source:    u.LibFiles,
+		Test:      u.TestFiles,
+		Example:   u.ExampleFiles,
+		Vendor:    u.VendorFiles,
+		Generated: u.GeneratedFiles,
+		Other:     concat(u.ScriptFiles, u.SupportFiles),
+	}
+	return md
+}
+
+func (u *BowerComponent) Metadata() Metadata {
+	md := Metadata{Language: "JavaScript", Ecosystem: "bower"}
+	md.Name, md.Version = manifestNameVersion(u.BowerJSON)
+	if md.Name == "" {
+		md.Name = filepath.Base(u.Dir)
+	}
+	return md
+}
+
+// Metadata returns the import path of the package as its Name, if it is known (otherwise, the
+// package name).
+func (u *GoPackage) Metadata() Metadata {
+	md := Metadata{Name: u.ImportPath, Language: "Go", Ecosystem: "go"}
+	if md.Name == "" || md.Name == "." {
+		md.Name = u.Package.Name
+	}
+	md.Files = Files{
+		Source: concat(u.GoFiles, u.CgoFiles, u.CFiles, u.CXXFiles, u.HFiles, u.SFiles),
+		Test:   concat(u.TestGoFiles, u.XTestGoFiles),
+	}
+	return md
+}
+
+func (u *PythonPackage) Metadata() Metadata {
+	return Metadata{Name: filepath.Base(u.Dir), Language: "Python", Ecosystem: "pypi"}
+}
+
+func (u *PythonModule) Metadata() Metadata {
+	return Metadata{Name: strings.TrimSuffix(filepath.Base(u.File), ".py"), Language: "Python", Ecosystem: "pypi"}
+}
+
+func (u *RubyGem) Metadata() Metadata {
+	return Metadata{
+		Name:      u.Name,
+		Language:  "Ruby",
+		Ecosystem: "rubygems",
+		Files:     Files{Source: u.SrcFiles, Test: u.TestFiles},
+	}
+}
+
+func (u *RubyApp) Metadata() Metadata {
+	return Metadata{
+		Name:      filepath.Base(u.Dir),
+		Language:  "Ruby",
+		Ecosystem: "rubygems",
+		Files:     Files{Source: u.SrcFiles, Test: u.TestFiles},
+	}
+}
+
+func (u *RubyFile) Metadata() Metadata {
+	return Metadata{Name: strings.TrimSuffix(filepath.Base(u.File), ".rb"), Language: "Ruby", Ecosystem: "rubygems"}
+}
+
+// Metadata returns "groupId:artifactId" as the Name of the project, if its pom.xml declares them
+// (otherwise, the base name of its directory), and the version in its pom.xml as its Version.
+func (u *JavaProject) Metadata() Metadata {
+	name := filepath.Base(u.Dir)
+	if u.ArtifactID != "" {
+		name = u.GroupID + ":" + u.ArtifactID
+	}
+	return Metadata{
+		Name:      name,
+		Language:  "Java",
+		Ecosystem: "maven",
+		Version:   u.Version,
+		Files:     Files{Source: u.SrcFiles, Test: u.TestFiles},
+	}
+}
+
+// Metadata categorizes the files of each file class by its name: "source", "src" and "lib" are
+// Source; "test" and "tests" are Test; "example" and "examples" are Example; "vendor" is Vendor;
+// "generated" is Generated (ignoring case). Files of other classes are Other.
+func (u *GenericUnit) Metadata() Metadata {
+	md := Metadata{Name: filepath.Base(u.Path()), Language: u.Language, Ecosystem: u.Ecosystem}
+	for class, files := range u.Files {
+		var list *[]string
+		switch strings.ToLower(class) {
+		case "source", "src", "lib":
+			list = &md.Files.Source
+		case "test", "tests":
+			list = &md.Files.Test
+		case "example", "examples":
+			list = &md.Files.Example
+		case "vendor":
+			list = &md.Files.Vendor
+		case "generated":
+			list = &md.Files.Generated
+		default:
+			list = &md.Files.Other
+		}
+		*list = append(*list, files...)
+	}
+	for _, list := range []*[]string{&md.Files.Source, &md.Files.Test, &md.Files.Example, &md.Files.Vendor, &md.Files.Generated, &md.Files.Other} {
+		sort.Strings(*list)
+	}
+	return md
+}
+
+var _, _, _, _, _, _, _, _, _, _ MetadataUnit = &NPMPackage{}, &BowerComponent{}, &GoPackage{}, &PythonPackage{}, &PythonModule{}, &RubyGem{}, &RubyApp{}, &RubyFile{}, &JavaProject{}, &GenericUnit{}
+
+// concat returns the concatenation of lists, or nil if they are all empty.
+func concat(lists ...[]string) []string {
+	var all []string
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return all
+}
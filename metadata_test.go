@@ -0,0 +1,70 @@
+package srcscan
+
+import (
+	"github.com/kr/pretty"
+	"go/build"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnitMetadata(t *testing.T) {
+	tests := []struct {
+		unit Unit
+		want Metadata
+	}{
+		{
+			&NPMPackage{
+				Dir:         "npm",
+				PackageJSON: []byte(`{"name":"mypkg","version":"1.2.3"}`),
+				LibFiles:    []string{"a.js"},
+				TestFiles:   []string{"test/b.js"},
+				ScriptFiles: []string{"bin/c.js"},
+				VendorFiles: []string{"vendor/d.js"},
+			},
+			Metadata{Name: "mypkg", Language: "JavaScript", Ecosystem: "npm", Version: "1.2.3", Files: Files{
+				Source: []string{"a.js"},
+				Test:   []string{"test/b.js"},
+				Vendor: []string{"vendor/d.js"},
+				Other:  []string{"bin/c.js"},
+			}},
+		},
+		{
+			&BowerComponent{Dir: "bower"},
+			Metadata{Name: "bower", Language: "JavaScript", Ecosystem: "bower"},
+		},
+		{
+			&GoPackage{Package: build.Package{Dir: "go", Name: "mypkg", ImportPath: "example.com/go", GoFiles: []string{"a.go"}, XTestGoFiles: []string{"a_test.go"}}},
+			Metadata{Name: "example.com/go", Language: "Go", Ecosystem: "go", Files: Files{Source: []string{"a.go"}, Test: []string{"a_test.go"}}},
+		},
+		{
+			&PythonModule{File: "python/myscript.py"},
+			Metadata{Name: "myscript", Language: "Python", Ecosystem: "pypi"},
+		},
+		{
+			&RubyGem{Dir: "ruby/gem", Name: "mygem", SrcFiles: []string{"lib/a.rb"}},
+			Metadata{Name: "mygem", Language: "Ruby", Ecosystem: "rubygems", Files: Files{Source: []string{"lib/a.rb"}}},
+		},
+		{
+			&JavaProject{Dir: "java/proj", TestFiles: []string{"src/test/java/A.java"}},
+			Metadata{Name: "proj", Language: "Java", Ecosystem: "maven", Files: Files{Test: []string{"src/test/java/A.java"}}},
+		},
+		{
+			&GenericUnit{Profile: "Bazel package", Language: "C++", Dir: "cc/lib", Files: map[string][]string{
+				"Src":   {"b.cc", "a.cc"},
+				"Tests": {"a_test.cc"},
+				"Data":  {"x.txt"},
+			}},
+			Metadata{Name: "lib", Language: "C++", Files: Files{
+				Source: []string{"a.cc", "b.cc"},
+				Test:   []string{"a_test.cc"},
+				Other:  []string{"x.txt"},
+			}},
+		},
+	}
+	for _, test := range tests {
+		if got := UnitMetadata(test.unit); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s %s: metadata:\n%v", UnitType(test.unit), test.unit.Path(), strings.Join(pretty.Diff(test.want, got), "\n"))
+		}
+	}
+}
@@ -19,7 +19,24 @@ type Profile struct {
 
 	TopLevelOnly bool
 
-	Unit func(abspath, relpath string, config Config, info os.FileInfo) Unit
+	// Owns, if set, matches the files that belong to the source unit created for a directory
+	// matched by this profile. Such files, in the directory and its subdirectories, are owned by
+	// the unit, and no profile's File matcher is evaluated for them.
+	Owns FileMatcher
+
+	// SkipDirs is a list of patterns of directories that are skipped when evaluating this profile
+	// and omitted from its source units, in addition to Config.SkipDirs. The syntax is the same
+	// as for Config.SkipDirs; a negated pattern here can re-include a directory for this profile.
+	SkipDirs []string
+
+	// ReadsManifest, if true, indicates that Unit reads a manifest file that the Dir matcher
+	// looks for (such as package.json), so that it fails for directories without one. Such a
+	// profile can't be named in Config.MarkedDirs.
+	ReadsManifest bool
+
+	// Unit creates the source unit for a matched directory or file. If it returns an error, the
+	// scan fails with a *ScanError.
+	Unit func(abspath, relpath string, config Config, info os.FileInfo) (Unit, error)
 }
 
 func (p Profile) DirMatches(path string, filenames []string) bool {
@@ -49,6 +66,9 @@ func (c FileInDir) DirMatches(path string, filenames []string) bool {
 	return false
 }
 
+// FileMatches returns false, because FileInDir only matches directories.
+func (c FileInDir) FileMatches(path string) bool { return false }
+
 // FileSuffixInDir matches directories containing a file with the specified filename suffix.
 type FileSuffixInDir struct{ Suffix string }
 
@@ -61,61 +81,80 @@ func (c FileSuffixInDir) DirMatches(path string, filenames []string) bool {
 	return false
 }
 
+// FileMatches returns false, because FileSuffixInDir only matches directories.
+func (c FileSuffixInDir) FileMatches(path string) bool { return false }
+
+// FileHasSuffix matches files (and directories) whose paths have the specified suffix.
 type FileHasSuffix struct{ Suffix string }
 
+func (c FileHasSuffix) DirMatches(path string, filenames []string) bool { return c.FileMatches(path) }
+
 func (c FileHasSuffix) FileMatches(path string) bool {
 	return strings.HasSuffix(path, c.Suffix)
 }
 
 var AllProfiles = []Profile{
 	Profile{
-		Name: "NPM package",
-		Dir:  FileInDir{"package.json"},
-		Unit: readNPMPackage,
+		Name:          "NPM package",
+		Dir:           FileInDir{"package.json"},
+		SkipDirs:      []string{"vendor"},
+		ReadsManifest: true,
+		Unit:          readNPMPackage,
 	},
 	Profile{
-		Name: "Bower component",
-		Dir:  FileInDir{"bower.json"},
-		Unit: readBowerComponent,
+		Name:          "Bower component",
+		Dir:           FileInDir{"bower.json"},
+		SkipDirs:      []string{"vendor"},
+		ReadsManifest: true,
+		Unit:          readBowerComponent,
 	},
 	Profile{
 		Name:         "Python package and module",
 		TopLevelOnly: true,
+		Owns:         FileHasSuffix{".py"},
 		Dir:          FileInDir{"__init__.py"},
 		File:         FileHasSuffix{".py"},
-		Unit: func(abspath, relpath string, config Config, info os.FileInfo) Unit {
+		Unit: func(abspath, relpath string, config Config, info os.FileInfo) (Unit, error) {
 			if info.IsDir() {
-				return &PythonPackage{relpath}
+				return &PythonPackage{Dir: relpath}, nil
 			} else {
-				return &PythonModule{relpath}
+				return &PythonModule{File: relpath}, nil
 			}
 		},
 	},
 	Profile{
-		Name: "Go package",
-		Dir:  FileSuffixInDir{".go"},
-		Unit: readGoPackage,
+		Name:     "Go package",
+		Dir:      FileSuffixInDir{".go"},
+		SkipDirs: []string{"vendor", "testdata"},
+		Unit:     readGoPackage,
 	},
 	Profile{
-		Name: "Java Maven project",
-		Dir:  FileInDir{"pom.xml"},
-		Unit: readJavaMavenProject,
+		Name:          "Java Maven project",
+		Dir:           FileInDir{"pom.xml"},
+		ReadsManifest: true,
+		Unit:          readJavaMavenProject,
 	},
 	Profile{
-		Name: "Ruby Gem",
-		Dir:  FileSuffixInDir{".gemspec"},
-		Unit: readRubyGem,
+		Name:          "Ruby Gem",
+		Dir:           FileSuffixInDir{".gemspec"},
+		Owns:          FileHasSuffix{".rb"},
+		SkipDirs:      []string{"**/vendor/bundle"},
+		ReadsManifest: true,
+		Unit:          readRubyGem,
 	},
 	Profile{
-		Name: "Ruby app",
-		Dir:  FileInDir{"config.ru"},
-		Unit: readRubyApp,
+		Name:     "Ruby app",
+		Dir:      FileInDir{"config.ru"},
+		Owns:     FileHasSuffix{".rb"},
+		SkipDirs: []string{"**/vendor/bundle"},
+		Unit:     readRubyApp,
 	},
 	Profile{
-		Name: "Ruby file",
-		File: FileHasSuffix{".rb"},
-		Unit: func(abspath, relpath string, config Config, info os.FileInfo) Unit {
-			return &RubyFile{relpath}
+		Name:     "Ruby file",
+		File:     FileHasSuffix{".rb"},
+		SkipDirs: []string{"**/vendor/bundle"},
+		Unit: func(abspath, relpath string, config Config, info os.FileInfo) (Unit, error) {
+			return &RubyFile{File: relpath}, nil
 		},
 	},
 }
@@ -0,0 +1,327 @@
+package srcscan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// A ProfileSpec declares a profile whose source units are GenericUnits, so that profiles can be
+// defined in a file instead of in Go code (see LoadProfiles).
+type ProfileSpec struct {
+	Name string
+
+	// Language and Ecosystem are set in the GenericUnits (see Metadata).
+	Language  string `json:",omitempty"`
+	Ecosystem string `json:",omitempty"`
+
+	// Dir and File match the directories and files that are source units, as for Profile.
+	Dir  *MatcherSpec `json:",omitempty"`
+	File *MatcherSpec `json:",omitempty"`
+
+	// TopLevelOnly, SkipDirs and Owns are as for Profile.
+	TopLevelOnly bool         `json:",omitempty"`
+	SkipDirs     []string     `json:",omitempty"`
+	Owns         *MatcherSpec `json:",omitempty"`
+
+	// Files classifies the files of the source unit created for a matched directory (see
+	// GenericUnit.Files).
+	Files []FileClassSpec `json:",omitempty"`
+}
+
+// A FileClassSpec declares a class of the files of a GenericUnit.
+type FileClassSpec struct {
+	Name string
+
+	// Patterns match the slash-separated paths of the files in the class, relative to the
+	// directory of the source unit. The syntax is that of gitignore(5): a pattern without a slash
+	// (such as "*_test.js") matches a file name at any depth, and a pattern with a slash (such as
+	// "src/main/**/*.java") is anchored at the directory of the source unit. The last pattern
+	// that matches a file determines whether it is in the class; a pattern prefixed with "!"
+	// excludes the files it matches.
+	Patterns []string
+}
+
+// A MatcherSpec declares a Matcher. Exactly one of its fields must be set.
+type MatcherSpec struct {
+	FileInDir       string            `json:",omitempty"`
+	FileSuffixInDir string            `json:",omitempty"`
+	FileHasSuffix   string            `json:",omitempty"`
+	NameGlob        string            `json:",omitempty"`
+	NameRegexp      string            `json:",omitempty"`
+	PathGlob        string            `json:",omitempty"`
+	PathInDir       string            `json:",omitempty"`
+	FileContains    *FileContainsSpec `json:",omitempty"`
+	AllOf           []*MatcherSpec    `json:",omitempty"`
+	AnyOf           []*MatcherSpec    `json:",omitempty"`
+	Not             *MatcherSpec      `json:",omitempty"`
+}
+
+// A FileContainsSpec declares a FileContains matcher.
+type FileContainsSpec struct {
+	Name    string `json:",omitempty"`
+	Pattern string
+}
+
+// Matcher returns the Matcher that s declares.
+func (s *MatcherSpec) Matcher() (Matcher, error) {
+	var ms []Matcher
+	add := func(m Matcher) { ms = append(ms, m) }
+	if s.FileInDir != "" {
+		add(FileInDir{s.FileInDir})
+	}
+	if s.FileSuffixInDir != "" {
+		add(FileSuffixInDir{s.FileSuffixInDir})
+	}
+	if s.FileHasSuffix != "" {
+		add(FileHasSuffix{s.FileHasSuffix})
+	}
+	if s.NameGlob != "" {
+		if _, err := filepath.Match(s.NameGlob, ""); err != nil {
+			return nil, fmt.Errorf("NameGlob %q: %s", s.NameGlob, err)
+		}
+		add(NameGlob{s.NameGlob})
+	}
+	if s.NameRegexp != "" {
+		re, err := regexp.Compile(s.NameRegexp)
+		if err != nil {
+			return nil, fmt.Errorf("NameRegexp: %s", err)
+		}
+		add(NameRegexp{re})
+	}
+	if s.PathGlob != "" {
+		add(PathGlob{s.PathGlob})
+	}
+	if s.PathInDir != "" {
+		add(PathInDir{s.PathInDir})
+	}
+	if s.FileContains != nil {
+		re, err := regexp.Compile(s.FileContains.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("FileContains: %s", err)
+		}
+		add(FileContains{s.FileContains.Name, re})
+	}
+	if s.AllOf != nil {
+		m, err := matcherSpecs(s.AllOf)
+		if err != nil {
+			return nil, fmt.Errorf("AllOf: %s", err)
+		}
+		add(AllOf(m))
+	}
+	if s.AnyOf != nil {
+		m, err := matcherSpecs(s.AnyOf)
+		if err != nil {
+			return nil, fmt.Errorf("AnyOf: %s", err)
+		}
+		add(AnyOf(m))
+	}
+	if s.Not != nil {
+		m, err := s.Not.Matcher()
+		if err != nil {
+			return nil, fmt.Errorf("Not: %s", err)
+		}
+		add(Not{m})
+	}
+	if len(ms) != 1 {
+		return nil, fmt.Errorf("matcher must have exactly 1 field set, not %d", len(ms))
+	}
+	return ms[0], nil
+}
+
+func matcherSpecs(specs []*MatcherSpec) ([]Matcher, error) {
+	ms := make([]Matcher, len(specs))
+	for i, spec := range specs {
+		if spec == nil {
+			return nil, errors.New("null matcher")
+		}
+		var err error
+		if ms[i], err = spec.Matcher(); err != nil {
+			return nil, err
+		}
+	}
+	return ms, nil
+}
+
+// Profile returns the profile that s declares.
+func (s *ProfileSpec) Profile() (Profile, error) {
+	p := Profile{Name: s.Name, TopLevelOnly: s.TopLevelOnly, SkipDirs: s.SkipDirs}
+	if s.Name == "" {
+		return p, errors.New("profile has no Name")
+	}
+	if s.Dir == nil && s.File == nil {
+		return p, fmt.Errorf("profile %q has neither Dir nor File", s.Name)
+	}
+	var err error
+	if s.Dir != nil {
+		if p.Dir, err = s.Dir.Matcher(); err != nil {
+			return p, fmt.Errorf("profile %q: Dir: %s", s.Name, err)
+		}
+	}
+	if s.File != nil {
+		if p.File, err = s.File.Matcher(); err != nil {
+			return p, fmt.Errorf("profile %q: File: %s", s.Name, err)
+		}
+	}
+	if s.Owns != nil {
+		if p.Owns, err = s.Owns.Matcher(); err != nil {
+			return p, fmt.Errorf("profile %q: Owns: %s", s.Name, err)
+		}
+	}
+
+	classes := make([]fileClass, len(s.Files))
+	for i, spec := range s.Files {
+		classes[i].name = spec.Name
+		for _, line := range spec.Patterns {
+			pat, ok := parseIgnorePattern(line)
+			if !ok {
+				return p, fmt.Errorf("profile %q: file class %q: invalid pattern %q", s.Name, spec.Name, line)
+			}
+			pat.base = "."
+			classes[i].rules = append(classes[i].rules, pat)
+		}
+	}
+	p.Unit = func(abspath, relpath string, config Config, info os.FileInfo) (Unit, error) {
+		if !info.IsDir() {
+			return &GenericUnit{Profile: s.Name, Language: s.Language, Ecosystem: s.Ecosystem, File: relpath}, nil
+		}
+		return readGenericUnit(s, p, classes, abspath, relpath, config)
+	}
+	return p, nil
+}
+
+// LoadProfiles reads a JSON array of ProfileSpecs from r and returns the profiles they declare.
+func LoadProfiles(r io.Reader) ([]Profile, error) {
+	var specs []*ProfileSpec
+	if err := json.NewDecoder(r).Decode(&specs); err != nil {
+		return nil, err
+	}
+	return specProfiles(specs)
+}
+
+// specProfiles returns the profiles that specs declare.
+func specProfiles(specs []*ProfileSpec) ([]Profile, error) {
+	profiles := make([]Profile, len(specs))
+	for i, spec := range specs {
+		if spec == nil {
+			return nil, errors.New("null profile")
+		}
+		var err error
+		if profiles[i], err = spec.Profile(); err != nil {
+			return nil, err
+		}
+	}
+	return profiles, nil
+}
+
+// LoadProfilesFile loads the profiles declared in the named file (see LoadProfiles) and adds them
+// to c.Profiles, after AllProfiles if c.Profiles is nil.
+func (c *Config) LoadProfilesFile(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	profiles, err := LoadProfiles(f)
+	if err != nil {
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+	if c.Profiles == nil {
+		c.Profiles = AllProfiles
+	}
+	c.Profiles = append(c.Profiles[:len(c.Profiles):len(c.Profiles)], profiles...)
+	return nil
+}
+
+// GenericUnit is a source unit created by a profile declared by a ProfileSpec.
+type GenericUnit struct {
+	Profile   string // name of the profile
+	Language  string `json:",omitempty"` // as given in the ProfileSpec
+	Ecosystem string `json:",omitempty"` // as given in the ProfileSpec
+	Dir       string `json:",omitempty"` // the matched directory, if a directory was matched
+	File      string `json:",omitempty"` // the matched file, if a file was matched
+
+	// Files maps the name of each file class of the profile to the files in the class (relative
+	// to Dir). Files in the subdirectories of Dir that the profile also matches are omitted,
+	// unless the profile is TopLevelOnly. A file is in the first class that includes it.
+	Files map[string][]string `json:",omitempty"`
+
+	Symlinked
+}
+
+// Path returns the matched directory or file.
+func (u *GenericUnit) Path() string {
+	if u.File != "" {
+		return u.File
+	}
+	return u.Dir
+}
+
+// A fileClass is a compiled FileClassSpec.
+type fileClass struct {
+	name  string
+	rules ignoreRules
+}
+
+func readGenericUnit(s *ProfileSpec, p Profile, classes []fileClass, absdir, reldir string, config Config) (Unit, error) {
+	u := &GenericUnit{Profile: p.Name, Language: s.Language, Ecosystem: s.Ecosystem, Dir: reldir}
+	if len(classes) == 0 {
+		return u, nil
+	}
+
+	lim := &fileLimiter{config: config}
+	err := walk(config.fs(), absdir, func(path string, info os.FileInfo, inerr error) (err error) {
+		if inerr != nil {
+			return inerr
+		}
+		if err := config.ctxErr(); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if config.pruned(path) {
+				return filepath.SkipDir
+			}
+			// Don't traverse into nested units of the same profile.
+			if path != absdir && !p.TopLevelOnly && p.Dir != nil {
+				infos, err := config.fs().ReadDir(path)
+				if err != nil {
+					return err
+				}
+				filenames := make([]string, len(infos))
+				for i, fi := range infos {
+					filenames[i] = fi.Name()
+				}
+				if dirMatches(config.fs(), p.Dir, path, filenames) {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || config.skipped(path, false) {
+			return nil
+		}
+		relpath, _ := filepath.Rel(absdir, path)
+		for _, class := range classes {
+			// The rules of a class "ignore" the files that are in it.
+			if class.rules.ignored(relpath, false) {
+				if !lim.add() {
+					return errStopWalk
+				}
+				if u.Files == nil {
+					u.Files = make(map[string][]string)
+				}
+				u.Files[class.name] = append(u.Files[class.name], filepath.ToSlash(relpath))
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
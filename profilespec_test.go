@@ -0,0 +1,85 @@
+package srcscan
+
+import (
+	"github.com/kr/pretty"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testProfileSpecs = `[
+	{
+		"Name": "Bazel package",
+		"Dir": {"AnyOf": [{"FileInDir": "BUILD"}, {"FileInDir": "BUILD.bazel"}]},
+		"SkipDirs": ["bazel-*"],
+		"Files": [
+			{"Name": "Test", "Patterns": ["*_test.cc"]},
+			{"Name": "Src", "Patterns": ["*.cc", "*.h", "!third_party/**"]}
+		]
+	},
+	{
+		"Name": "Starlark file",
+		"File": {"FileHasSuffix": ".bzl"}
+	}
+]`
+
+func TestLoadProfiles(t *testing.T) {
+	profiles, err := LoadProfiles(strings.NewReader(testProfileSpecs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := Default
+	config.Base = ""
+	config.Profiles = profiles
+	config.FileSystem = MapFS(map[string]string{
+		"BUILD":                 "",
+		"a.cc":                  "",
+		"a.h":                   "",
+		"a_test.cc":             "",
+		"README":                "",
+		"third_party/x.cc":      "",
+		"bazel-out/gen.cc":      "",
+		"defs.bzl":              "",
+		"sub/BUILD.bazel":       "",
+		"sub/b.cc":              "",
+		"sub/tools/c.cc":        "",
+		"sub/tools/rules.bzl":   "",
+		"other/BUILD.notreally": "",
+		"other/d.cc":            "",
+	})
+
+	units, err := config.Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Unit{
+		&GenericUnit{Profile: "Bazel package", Dir: ".", Files: map[string][]string{
+			"Src":  {"a.cc", "a.h", "other/d.cc"},
+			"Test": {"a_test.cc"},
+		}},
+		&GenericUnit{Profile: "Bazel package", Dir: "sub", Files: map[string][]string{
+			"Src": {"b.cc", "tools/c.cc"},
+		}},
+		&GenericUnit{Profile: "Starlark file", File: "defs.bzl"},
+		&GenericUnit{Profile: "Starlark file", File: "sub/tools/rules.bzl"},
+	}
+	if !reflect.DeepEqual(want, units) {
+		t.Errorf("units:\n%v", strings.Join(pretty.Diff(want, units), "\n"))
+	}
+}
+
+func TestLoadProfiles_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		`[{"Dir": {"FileInDir": "x"}}]`,
+		`[{"Name": "x"}]`,
+		`[{"Name": "x", "Dir": {}}]`,
+		`[{"Name": "x", "Dir": {"FileInDir": "x", "PathInDir": "y"}}]`,
+		`[{"Name": "x", "File": {"NameRegexp": "("}}]`,
+		`[{"Name": "x", "File": {"Not": {"AllOf": [null]}}}]`,
+	} {
+		if _, err := LoadProfiles(strings.NewReader(spec)); err == nil {
+			t.Errorf("%s: got no error", spec)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package srcscan
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// RepoConfigFile is the name of the file in the scanned directory that holds its RepoConfig.
+const RepoConfigFile = ".srcscan"
+
+// A RepoConfig holds the settings for scanning a repository that are checked into it, in the JSON
+// file RepoConfigFile at its root. They are merged over the Config used to scan the repository if
+// Config.RepoConfig is set. Paths are slash-separated and relative to the root of the repository.
+type RepoConfig struct {
+	// SkipDirs is appended to Config.SkipDirs.
+	SkipDirs []string `json:",omitempty"`
+
+	// The lists in NPMPackage and Ruby are appended to the corresponding lists in Config.
+	NPMPackage NPMPackageConfig
+	Ruby       RubyConfig
+
+	// Profiles declares profiles that are added after Config.Profiles (or AllProfiles).
+	Profiles []*ProfileSpec `json:",omitempty"`
+
+	// MarkedDirs is added to Config.MarkedDirs.
+	MarkedDirs map[string]string `json:",omitempty"`
+}
+
+// withRepoConfig returns c with the RepoConfig in dir merged over it, if c.RepoConfig is set and
+// there is one.
+func (c Config) withRepoConfig(dir string) (Config, error) {
+	if !c.RepoConfig {
+		return c, nil
+	}
+	path := filepath.Join(dir, RepoConfigFile)
+	data, err := readFile(c.fs(), path)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return c, &ScanError{Path: path, Err: err}
+	}
+
+	var rc RepoConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rc); err != nil {
+		return c, &ScanError{Path: path, Err: err}
+	}
+	if err := c.merge(&rc); err != nil {
+		return c, &ScanError{Path: path, Err: err}
+	}
+	return c, nil
+}
+
+// merge merges rc over c.
+func (c *Config) merge(rc *RepoConfig) error {
+	c.SkipDirs = append(c.SkipDirs[:len(c.SkipDirs):len(c.SkipDirs)], rc.SkipDirs...)
+	appendStringLists(&c.NPMPackage, rc.NPMPackage)
+	appendStringLists(&c.Ruby, rc.Ruby)
+
+	if len(rc.Profiles) > 0 {
+		if c.Profiles == nil {
+			c.Profiles = AllProfiles
+		}
+		profiles, err := specProfiles(rc.Profiles)
+		if err != nil {
+			return err
+		}
+		c.Profiles = append(c.Profiles[:len(c.Profiles):len(c.Profiles)], profiles...)
+	}
+
+	if len(rc.MarkedDirs) > 0 {
+		marked := make(map[string]string, len(c.MarkedDirs)+len(rc.MarkedDirs))
+		for dir, profile := range c.MarkedDirs {
+			marked[dir] = profile
+		}
+		for dir, profile := range rc.MarkedDirs {
+			marked[dir] = profile
+		}
+		c.MarkedDirs = marked
+	}
+	return nil
+}
+
+// appendStringLists appends each []string field of src, which must be a struct, to the same field
+// of the struct that dst points to.
+func appendStringLists(dst, src interface{}) {
+	d, s := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src)
+	for i := 0; i < s.NumField(); i++ {
+		if f := s.Field(i); f.Type() == reflect.TypeOf([]string(nil)) && f.Len() > 0 {
+			df := d.Field(i)
+			df.Set(reflect.AppendSlice(df.Slice3(0, df.Len(), df.Len()), f))
+		}
+	}
+}
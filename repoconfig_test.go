@@ -0,0 +1,75 @@
+package srcscan
+
+import (
+	"github.com/kr/pretty"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScan_RepoConfig(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.PathIndependent = true
+	config.RepoConfig = true
+	config.FileSystem = MapFS(map[string]string{
+		RepoConfigFile: `{
+			"SkipDirs": ["experiments"],
+			"NPMPackage": {"ScriptDirs": ["devtools"]},
+			"Profiles": [
+				{"Name": "Makefile project", "Dir": {"FileInDir": "Makefile"}},
+				{"Name": "Scripts", "Dir": {"FileInDir": "scripts.txt"}}
+			],
+			"MarkedDirs": {"py/tools": "Python package and module", "tools": "Scripts"}
+		}`,
+		"experiments/x/package.json": `{}`,
+		"js/package.json":            `{}`,
+		"js/devtools/build.js":       "",
+		"js/lib/main.js":             "",
+		"py/tools/x.py":              "",
+		"c/Makefile":                 "",
+		"tools/deploy.sh":            "",
+	})
+
+	units, err := config.Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The directories py/tools and tools are marked, although their profiles' Dir matchers don't
+	// match them.
+	want := []Unit{
+		&NPMPackage{Dir: "js", PackageJSON: []byte(`{}`), ScriptFiles: []string{"devtools/build.js"}, LibFiles: []string{"lib/main.js"}},
+		&PythonPackage{Dir: "py/tools"},
+		&GenericUnit{Profile: "Makefile project", Dir: "c"},
+		&GenericUnit{Profile: "Scripts", Dir: "tools"},
+	}
+	if !reflect.DeepEqual(want, units) {
+		t.Errorf("units:\n%v", strings.Join(pretty.Diff(want, units), "\n"))
+	}
+
+	// The RepoConfig is ignored unless Config.RepoConfig is set.
+	config.RepoConfig = false
+	if units, err = config.Scan("."); err != nil {
+		t.Fatal(err)
+	} else if len(units) != 3 {
+		t.Errorf("got %d units %v without RepoConfig, want 3", len(units), units)
+	}
+}
+
+func TestScan_RepoConfig_Invalid(t *testing.T) {
+	config := Default
+	config.RepoConfig = true
+	for _, rc := range []string{`{"SkipDir": ["typo"]}`, `{"MarkedDirs": {"a": "No such profile"}}`, `{"MarkedDirs": {"a": "Ruby file"}}`, `[`} {
+		config.FileSystem = MapFS(map[string]string{RepoConfigFile: rc, "a/x": ""})
+		if _, err := config.Scan("."); err == nil {
+			t.Errorf("%s: got no error", rc)
+		}
+	}
+
+	// A profile that reads a manifest is rejected up front, rather than failing on the marked
+	// directory.
+	config.FileSystem = MapFS(map[string]string{RepoConfigFile: `{"MarkedDirs": {"a": "NPM package"}}`, "a/x": ""})
+	if _, err := config.Scan("."); err == nil || !strings.Contains(err.Error(), "reads a manifest") {
+		t.Errorf("got error %v, want MarkedDirs error", err)
+	}
+}
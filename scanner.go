@@ -0,0 +1,502 @@
+package srcscan
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A match is a directory or file that matched a profile during a scan.
+type match struct {
+	profile int // index of the matching profile
+	path    string
+	info    os.FileInfo
+	link    string // path of the symbolic link through which path was reached, if any
+}
+
+// walkState is the state of the walk that is passed down from a directory to its entries.
+type walkState struct {
+	active    []bool        // whether each profile is evaluated
+	ancestors []os.FileInfo // the directories being walked, if following symbolic links
+	link      string        // path of the symbolic link through which the directory was reached
+	skipped   []bool        // whether each profile skips the directory (nil if none does)
+	depth     int           // depth of the directory below the scanned directory
+	owners    []FileMatcher // Profile.Owns of the source units enclosing the directory
+}
+
+// owned reports whether the file at path in fs is owned by a source unit enclosing it.
+func (st walkState) owned(fs FileSystem, path string) bool {
+	for _, owns := range st.owners {
+		if fileMatches(fs, owns, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluates reports whether the profile with index i is evaluated in the directory.
+func (st walkState) evaluates(i int) bool {
+	return st.active[i] && (st.skipped == nil || !st.skipped[i])
+}
+
+// scanner holds the state of a single scan of a directory tree.
+type scanner struct {
+	config   Config
+	profiles []Profile
+
+	// sem limits the number of additional goroutines walking directories; it is nil if the walk
+	// is sequential.
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	// matches receives directories and files that matched a profile as they are walked.
+	matches chan match
+
+	// rootReal is the real path of the scanned directory, if following symbolic links.
+	rootReal string
+
+	// entries is the number of files and directories visited, for Config.MaxEntries.
+	entries int64
+
+	// marked maps the keys of the directories in Config.MarkedDirs to the indexes of their
+	// profiles.
+	marked map[string]int
+
+	// skips determines the directories skipped by each profile, per Config.SkipDirs and
+	// Profile.SkipDirs; an entry is nil if the profile skips no directories.
+	skips []*skipper
+
+	mu           sync.Mutex
+	skippedLinks ScanErrors // symbolic links that were not followed
+	truncated    ScanErrors // directories and units truncated because of a limit
+	err          error      // first error encountered, unless config.KeepGoing is set
+	errs         ScanErrors // errors encountered, if config.KeepGoing is set
+}
+
+func (c Config) newScanner() *scanner {
+	s := &scanner{config: c, profiles: c.Profiles}
+	if s.profiles == nil {
+		s.profiles = AllProfiles
+	}
+	if c.FileSystem == nil {
+		s.config.Base, _ = filepath.Abs(c.Base)
+	} else {
+		s.config.Base = filepath.Clean(c.Base)
+	}
+	if c.Concurrency > 1 {
+		s.sem = make(chan struct{}, c.Concurrency-1)
+	}
+	return s
+}
+
+// A built is a source unit created from a match, or the error encountered creating it.
+type built struct {
+	match
+	unit Unit
+	err  *ScanError
+}
+
+// builtInWalkOrder sorts units by profile and then in the order that filepath.Walk would visit
+// their paths.
+type builtInWalkOrder []built
+
+func (b builtInWalkOrder) Len() int      { return len(b) }
+func (b builtInWalkOrder) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b builtInWalkOrder) Less(i, j int) bool {
+	if b[i].profile != b[j].profile {
+		return b[i].profile < b[j].profile
+	}
+	return walkOrderLess(b[i].path, b[j].path)
+}
+
+// run walks the tree at dir and creates a source unit for each match, calling emit for each one
+// as soon as it is created. Calls to emit are serialized, but their order depends on the
+// scheduling of the walk and of unit creation. If emit returns an error, the scan stops and run
+// returns that error. If ctx is done, the scan stops and run returns ctx.Err().
+func (s *scanner) run(ctx context.Context, dir string, emit func(built) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	info, err := s.config.fs().Lstat(dir)
+	if err == nil && s.followSymlinks() {
+		info, err = s.config.fs().Stat(dir)
+		if err == nil {
+			s.rootReal, err = filepath.EvalSymlinks(dir)
+		}
+		if err == nil {
+			s.rootReal, err = filepath.Abs(s.rootReal)
+		}
+	}
+	if err != nil {
+		return &ScanError{Path: dir, Err: err}
+	}
+
+	s.config.ctx = ctx
+	s.config.scanDir = dir
+	if s.config.Stats {
+		s.config.stats = newStats(s.profiles)
+		defer func(start time.Time) { s.config.stats.Duration = time.Since(start) }(time.Now())
+	}
+	if s.config.RespectGitIgnore {
+		s.config.ignore = newIgnorer(s.config.fs(), s.config.FileSystem == nil, dir)
+	}
+	if len(s.config.MarkedDirs) > 0 {
+		byName := make(map[string]int, len(s.profiles))
+		for i := len(s.profiles) - 1; i >= 0; i-- {
+			byName[s.profiles[i].Name] = i
+		}
+		s.marked = make(map[string]int, len(s.config.MarkedDirs))
+		for rel, name := range s.config.MarkedDirs {
+			i, ok := byName[name]
+			if !ok {
+				return &ScanError{Path: dir, Err: fmt.Errorf("MarkedDirs: no profile named %q for %s", name, rel)}
+			}
+			if s.profiles[i].Dir == nil {
+				return &ScanError{Path: dir, Err: fmt.Errorf("MarkedDirs: profile %q for %s does not match directories", name, rel)}
+			}
+			if s.profiles[i].ReadsManifest {
+				return &ScanError{Path: dir, Err: fmt.Errorf("MarkedDirs: profile %q for %s reads a manifest", name, rel)}
+			}
+			s.marked[s.key(filepath.Join(dir, filepath.FromSlash(rel)))] = i
+		}
+	}
+
+	s.skips = make([]*skipper, len(s.profiles))
+	for i, profile := range s.profiles {
+		// The profile's patterns take precedence, so that it can re-include directories.
+		patterns := append(append([]string(nil), s.config.SkipDirs...), profile.SkipDirs...)
+		if len(patterns) > 0 {
+			s.skips[i] = newSkipper(patterns, s.config.FileSystem == nil, dir)
+		}
+	}
+
+	s.matches = make(chan match)
+	go func() {
+		st := walkState{active: make([]bool, len(s.profiles))}
+		for i := range st.active {
+			st.active[i] = true
+		}
+		s.walk(dir, info, st)
+		s.wg.Wait()
+		close(s.matches)
+	}()
+
+	results := make(chan built)
+	var wg sync.WaitGroup
+	for i := 0; i < s.config.Concurrency || i == 0; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range s.matches {
+				results <- s.build(m)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Keep receiving after a failure, so that the walkers and builders can finish.
+	for b := range results {
+		if s.failed() {
+			continue
+		}
+		if b.err != nil {
+			s.fail(b.err)
+			continue
+		}
+		if err := emit(b); err != nil {
+			s.stop(err)
+		}
+	}
+	return s.err
+}
+
+// build creates the source unit for m.
+func (s *scanner) build(m match) (b built) {
+	b.match = m
+	if s.failed() {
+		return
+	}
+	profile := s.profiles[m.profile]
+	config := s.config
+	config.skip = s.skips[m.profile]
+	config.truncate = func(err error) {
+		s.truncate(&ScanError{Path: m.path, Profile: profile.Name, Err: err})
+	}
+	relpath, abspath, err := config.relAbsPath(m.path)
+	if err == nil {
+		start := time.Now()
+		b.unit, err = profile.Unit(abspath, relpath, config, m.info)
+		s.countUnit(m.profile, time.Since(start), err)
+	}
+	if u, ok := b.unit.(symlinker); ok && err == nil && m.link != "" {
+		var link string
+		if link, _, err = config.relAbsPath(m.link); err == nil {
+			u.setSymlink(link)
+		}
+	}
+	if err != nil {
+		if cerr := s.config.ctxErr(); cerr != nil {
+			// The reader was interrupted; this is not an error in m.
+			s.stop(cerr)
+			b.unit = nil
+			return
+		}
+		b.err = &ScanError{Path: m.path, Profile: profile.Name, Err: err}
+	}
+	return
+}
+
+// addMatch sends m to be built.
+func (s *scanner) addMatch(m match) {
+	if s.config.stats != nil {
+		s.mu.Lock()
+		s.config.stats.Profiles[m.profile].Matches++
+		s.mu.Unlock()
+	}
+	s.matches <- m
+}
+
+// fail records an error. Unless config.KeepGoing is set, it stops the scan.
+func (s *scanner) fail(err *ScanError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.config.KeepGoing {
+		s.errs = append(s.errs, err)
+	} else if s.err == nil {
+		s.err = err
+	}
+}
+
+// stop stops the scan, recording err as its result.
+func (s *scanner) stop(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// failed reports whether the scan has stopped, stopping it if its context is done.
+func (s *scanner) failed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = s.config.ctxErr()
+	}
+	return s.err != nil
+}
+
+// walk visits path and, if it is a directory, its descendants. Only profiles whose entry in
+// st.active is true, and that don't skip the directory, are evaluated; a profile is deactivated
+// below a directory it matched if it is TopLevelOnly. File profiles are not evaluated for files
+// owned by a source unit matched in an enclosing directory. Subdirectories are walked in new goroutines while s.sem has capacity.
+func (s *scanner) walk(path string, info os.FileInfo, st walkState) {
+	if s.failed() {
+		return
+	}
+	active := st.active
+
+	if !info.IsDir() {
+		if s.config.stats != nil {
+			atomic.AddInt64(&s.config.stats.Files, 1)
+		}
+		if st.owned(s.config.fs(), path) {
+			return
+		}
+		for i, profile := range s.profiles {
+			if st.evaluates(i) && profile.File != nil && fileMatches(s.config.fs(), profile.File, path) {
+				s.addMatch(match{i, path, info, st.link})
+			}
+		}
+		return
+	}
+
+	if s.config.MaxDepth > 0 && st.depth > s.config.MaxDepth {
+		s.truncate(&ScanError{Path: path, Err: ErrMaxDepth})
+		return
+	}
+	infos, err := s.config.fs().ReadDir(path)
+	if err != nil {
+		s.fail(&ScanError{Path: path, Err: err})
+		return
+	}
+	if s.config.stats != nil {
+		atomic.AddInt64(&s.config.stats.Dirs, 1)
+	}
+	filenames := make([]string, len(infos))
+	for i, fi := range infos {
+		filenames[i] = fi.Name()
+	}
+
+	marked := -1
+	if s.marked != nil {
+		if i, ok := s.marked[s.key(path)]; ok {
+			marked = i
+		}
+	}
+	var sub []bool // profiles active below path, if different from active
+	for i, profile := range s.profiles {
+		if st.evaluates(i) && (i == marked || profile.Dir != nil && dirMatches(s.config.fs(), profile.Dir, path, filenames)) {
+			s.addMatch(match{i, path, info, st.link})
+			if profile.Owns != nil {
+				st.owners = append(st.owners[:len(st.owners):len(st.owners)], profile.Owns)
+			}
+			if profile.TopLevelOnly {
+				if sub == nil {
+					sub = append([]bool(nil), active...)
+				}
+				sub[i] = false
+			}
+		}
+	}
+	if sub == nil {
+		sub = active
+	}
+	if !anyTrue(sub) {
+		return
+	}
+	st.active = sub
+	st.depth++
+	if s.followSymlinks() {
+		st.ancestors = append(st.ancestors[:len(st.ancestors):len(st.ancestors)], info)
+	}
+
+	for _, fi := range infos {
+		if n := atomic.AddInt64(&s.entries, 1); s.config.MaxEntries > 0 && n > int64(s.config.MaxEntries) {
+			s.truncate(&ScanError{Path: path, Err: ErrMaxEntries})
+			break
+		}
+		filename := filepath.Join(path, fi.Name())
+		fi, st := fi, st
+		if fi.Mode()&os.ModeSymlink != 0 && s.followSymlinks() {
+			target, follow := s.followSymlink(filename, st.ancestors)
+			if follow {
+				fi = target
+				if st.link == "" {
+					st.link = filename
+				}
+			}
+		}
+		if fi.IsDir() {
+			var prune bool
+			if st.skipped, prune = s.skipDir(filename, st); prune {
+				continue
+			}
+			select {
+			case s.sem <- struct{}{}:
+				s.wg.Add(1)
+				go func() {
+					defer s.wg.Done()
+					defer func() { <-s.sem }()
+					s.walk(filename, fi, st)
+				}()
+				continue
+			default:
+			}
+		}
+		s.walk(filename, fi, st)
+	}
+}
+
+// skipDir determines which profiles skip the directory at path, which is an entry of the directory
+// with the given walk state. It reports whether the directory can be pruned, because every profile
+// that is active in it skips it and everything below it.
+func (s *scanner) skipDir(path string, st walkState) (skipped []bool, prune bool) {
+	prune = true
+	for i, sk := range s.skips {
+		if !st.active[i] {
+			continue
+		}
+		if sk == nil {
+			prune = false
+			continue
+		}
+		rel, _ := sk.rel(path)
+		if sk.skipDir(rel, st.skipped != nil && st.skipped[i]) {
+			if skipped == nil {
+				skipped = make([]bool, len(s.skips))
+			}
+			skipped[i] = true
+			prune = prune && sk.prune(rel)
+		} else {
+			prune = false
+		}
+	}
+	return skipped, prune
+}
+
+// key returns the key of path, as for an ignorer.
+func (s *scanner) key(path string) string {
+	return pathKey(s.config.FileSystem == nil, path)
+}
+
+func (s *scanner) followSymlinks() bool {
+	return s.config.FollowSymlinks && s.config.FileSystem == nil
+}
+
+// followSymlink determines whether to follow the symbolic link at path, which is in the directory
+// whose ancestors (including itself) are given, and returns the FileInfo of its target if so. Only
+// links to directories are followed. Links that resolve outside the scanned directory, or to one
+// of the ancestors (which would cause a cycle), are recorded in s.skippedLinks.
+func (s *scanner) followSymlink(path string, ancestors []os.FileInfo) (os.FileInfo, bool) {
+	fi, err := s.config.fs().Stat(path)
+	if err != nil || !fi.IsDir() {
+		return nil, false // dangling link or link to a file
+	}
+
+	real, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		real, err = filepath.Abs(real)
+	}
+	if err != nil {
+		s.fail(&ScanError{Path: path, Err: err})
+		return nil, false
+	}
+	if _, within := keyRel(s.rootReal, real); !within && real != s.rootReal {
+		s.skipLink(&ScanError{Path: path, Err: ErrSymlinkEscape})
+		return nil, false
+	}
+	for _, anc := range ancestors {
+		if os.SameFile(anc, fi) {
+			s.skipLink(&ScanError{Path: path, Err: ErrSymlinkCycle})
+			return nil, false
+		}
+	}
+	return fi, true
+}
+
+// countUnit records in s.config.stats, if set, that a source unit of the profile with index i was
+// created (or failed with err) in time t.
+func (s *scanner) countUnit(i int, t time.Duration, err error) {
+	if s.config.stats == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := &s.config.stats.Profiles[i]
+	if err == nil {
+		p.Units++
+	} else {
+		p.Errors++
+	}
+	p.Time += t
+}
+
+func (s *scanner) truncate(err *ScanError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.truncated = append(s.truncated, err)
+}
+
+func (s *scanner) skipLink(err *ScanError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.skippedLinks = append(s.skippedLinks, err)
+}
@@ -0,0 +1,166 @@
+package srcscan
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A skipPattern is a compiled Config.SkipDirs pattern.
+type skipPattern struct {
+	ignorePattern
+	line string // the pattern as given
+
+	// components are the path components of an anchored negated pattern, which are used to
+	// determine whether it can match below a skipped directory. They are nil for other patterns.
+	components []string
+	anchored   bool
+}
+
+// parseSkipPattern parses a SkipDirs pattern. The syntax is that of gitignore(5), except that
+// patterns only ever match directories and a trailing "/**" is equivalent to matching the
+// directory itself.
+func parseSkipPattern(line string) (pat skipPattern, ok bool) {
+	pat.line = line
+	if strings.HasSuffix(line, "/**") {
+		line = strings.TrimSuffix(line, "/**")
+		if trimmed := strings.TrimPrefix(line, "!"); !strings.Contains(trimmed, "/") {
+			// The pattern was anchored by the slash before "**".
+			line = line[:len(line)-len(trimmed)] + "/" + trimmed
+		}
+	}
+	pat.ignorePattern, ok = parseIgnorePattern(line)
+	if !ok {
+		return pat, false
+	}
+	glob := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(line), "!"), "/")
+	pat.anchored = strings.Contains(glob, "/")
+	if pat.negate && pat.anchored {
+		pat.components = strings.Split(strings.TrimPrefix(glob, "/"), "/")
+	}
+	return pat, true
+}
+
+// mayMatchBelow reports whether pat may match a path below the directory rel (slash-separated,
+// relative to the scanned directory).
+func (pat skipPattern) mayMatchBelow(rel string) bool {
+	if !pat.anchored {
+		return true
+	}
+	dirs := strings.Split(rel, "/")
+	for i, dir := range dirs {
+		if i >= len(pat.components) {
+			return false
+		}
+		comp := pat.components[i]
+		if comp == "**" {
+			return true
+		}
+		if match, _ := path.Match(strings.Replace(comp, "[!", "[^", -1), dir); !match {
+			return false
+		}
+	}
+	return len(pat.components) > len(dirs)
+}
+
+// A skipper determines which directories in a scan are skipped by the Config.SkipDirs patterns.
+// As for an ignorer, paths are compared by key.
+type skipper struct {
+	abs  bool
+	root string // key of the scanned directory
+	pats []skipPattern
+}
+
+func newSkipper(patterns []string, abs bool, dir string) *skipper {
+	sk := &skipper{abs: abs, root: pathKey(abs, dir)}
+	for _, line := range patterns {
+		if pat, ok := parseSkipPattern(line); ok {
+			sk.pats = append(sk.pats, pat)
+		}
+	}
+	return sk
+}
+
+// without returns a skipper with the patterns of sk other than those given exactly by lines. It
+// returns nil if sk is nil or has no other patterns.
+func (sk *skipper) without(lines []string) *skipper {
+	if sk == nil {
+		return nil
+	}
+	out := &skipper{abs: sk.abs, root: sk.root}
+	for _, pat := range sk.pats {
+		if !contains(lines, pat.line) {
+			out.pats = append(out.pats, pat)
+		}
+	}
+	if len(out.pats) == 0 {
+		return nil
+	}
+	return out
+}
+
+// rel returns the slash-separated path of path relative to the scanned directory, if it is below
+// it.
+func (sk *skipper) rel(path string) (string, bool) {
+	return keyRel(sk.root, pathKey(sk.abs, path))
+}
+
+// skipDir reports whether the directory rel (see rel) is skipped, given whether its parent
+// directory is. The last pattern that matches rel determines whether it is skipped; if none
+// matches, it is skipped if its parent is. Unlike in gitignore(5), a negated pattern re-includes a
+// directory even if its parent is skipped.
+func (sk *skipper) skipDir(rel string, parentSkipped bool) bool {
+	for i := len(sk.pats) - 1; i >= 0; i-- {
+		if sk.pats[i].re.MatchString(rel) {
+			return !sk.pats[i].negate
+		}
+	}
+	return parentSkipped
+}
+
+// prune reports whether no directory below the skipped directory rel can be re-included by a
+// negated pattern, so that it need not be walked at all.
+func (sk *skipper) prune(rel string) bool {
+	for _, pat := range sk.pats {
+		if pat.negate && pat.mayMatchBelow(rel) {
+			return false
+		}
+	}
+	return true
+}
+
+// skipped reports whether the file or directory at path is in a skipped directory (or is one).
+// Paths that are not below the scanned directory, and all paths outside of a scan, are never
+// skipped.
+func (c Config) skipped(path string, isDir bool) bool {
+	if c.skip == nil {
+		return false
+	}
+	rel, ok := c.skip.rel(path)
+	if !ok {
+		return false
+	}
+	if !isDir {
+		rel = filepath.ToSlash(filepath.Dir(filepath.FromSlash(rel)))
+		if rel == "." {
+			return false
+		}
+	}
+	var skip bool
+	for i := 0; i <= len(rel); i++ {
+		if i == len(rel) || rel[i] == '/' {
+			skip = c.skip.skipDir(rel[:i], skip)
+		}
+	}
+	return skip
+}
+
+// pruned reports whether the directory at path is skipped and contains no re-included
+// directories, so that walkers need not descend into it.
+func (c Config) pruned(path string) bool {
+	if !c.skipped(path, true) {
+		return false
+	}
+	rel, _ := c.skip.rel(path)
+	return c.skip.prune(rel)
+}
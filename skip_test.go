@@ -0,0 +1,127 @@
+package srcscan
+
+import (
+	"github.com/kr/pretty"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSkipper(t *testing.T) {
+	sk := newSkipper([]string{"node_modules", "/third_party/**", "web/build", "vendor", "!vendor/keep"}, false, ".")
+	tests := []struct {
+		rel     string
+		skipped bool
+		pruned  bool
+	}{
+		{"node_modules", true, true},
+		{"a/b/node_modules", true, true},
+		{"third_party", true, true},
+		{"a/third_party", false, false},
+		{"web/build", true, true},
+		{"build", false, false},
+		{"a/web/build", false, false},
+		{"vendor", true, false},
+		{"vendor/other", true, true},
+		{"vendor/keep", false, false},
+		{"a/vendor", true, true},
+	}
+	for _, test := range tests {
+		var skip bool
+		dirs := strings.Split(test.rel, "/")
+		for i := range dirs {
+			skip = sk.skipDir(strings.Join(dirs[:i+1], "/"), skip)
+		}
+		if skip != test.skipped {
+			t.Errorf("%s: got skipped %v, want %v", test.rel, skip, test.skipped)
+		}
+		if pruned := skip && sk.prune(test.rel); pruned != test.pruned {
+			t.Errorf("%s: got pruned %v, want %v", test.rel, pruned, test.pruned)
+		}
+	}
+}
+
+func TestScan_SkipDirs(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.PathIndependent = true
+	config.SkipDirs = []string{"**/node_modules", "/third_party/**", "web/build", "vendor", "!vendor/keep", "gem/lib/gen"}
+	config.FileSystem = MapFS(map[string]string{
+		"a/node_modules/x/package.json": `{}`,
+		"lib/build/package.json":        `{}`,
+		"web/build/package.json":        `{}`,
+		"third_party/x/package.json":    `{}`,
+		"vendor/keep/package.json":      `{}`,
+		"vendor/other/package.json":     `{}`,
+		"gem/x.gemspec":                 "",
+		"gem/lib/x.rb":                  "",
+		"gem/lib/gen/y.rb":              "",
+	})
+
+	units, err := config.Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Unit{
+		&NPMPackage{Dir: "lib/build", PackageJSON: []byte(`{}`)},
+		&NPMPackage{Dir: "vendor/keep", PackageJSON: []byte(`{}`)},
+		&RubyGem{Dir: "gem", Name: "x", GemSpecFile: "x.gemspec", SrcFiles: []string{"lib/x.rb"}},
+	}
+	if !reflect.DeepEqual(want, units) {
+		t.Errorf("units:\n%v", strings.Join(pretty.Diff(want, units), "\n"))
+	}
+}
+
+func TestScan_SkipDirs_NPMVendorFiles(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.PathIndependent = true
+	config.SkipDirs = append(config.SkipDirs, "/web/static/**")
+	config.FileSystem = MapFS(map[string]string{
+		"web/package.json":            `{}`,
+		"web/a.js":                    "",
+		"web/static/huge/x.js":        "",
+		"web/vendor/v.js":             "",
+		"web/bower_components/b/b.js": "",
+	})
+
+	units, err := config.Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Vendor directories skipped by default are listed, but the user's pattern applies.
+	want := []Unit{
+		&NPMPackage{Dir: "web", PackageJSON: []byte(`{}`), LibFiles: []string{"a.js"}, VendorFiles: []string{"bower_components/b/b.js", "vendor/v.js"}},
+	}
+	if !reflect.DeepEqual(want, units) {
+		t.Errorf("units:\n%v", strings.Join(pretty.Diff(want, units), "\n"))
+	}
+}
+
+func TestScan_ProfileSkipDirs(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.PathIndependent = true
+	config.FileSystem = MapFS(map[string]string{
+		"vendor/gems/x/x.gemspec":        "",
+		"vendor/gems/x/lib/x.rb":         "",
+		"vendor/github.com/y/y.go":       "package y\n",
+		"web/testdata/pkg/__init__.py":   "",
+		"web/testdata/pkg/main.go":       "package main\n",
+		"web/node_modules/z/__init__.py": "",
+		"vendor/bundle/gems/a.rb":        "",
+		"other/vendor/bundle/z.rb":       "",
+	})
+
+	units, err := config.Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Unit{
+		&PythonPackage{Dir: "web/testdata/pkg"},
+		&RubyGem{Dir: "vendor/gems/x", Name: "x", GemSpecFile: "x.gemspec", SrcFiles: []string{"lib/x.rb"}},
+	}
+	if !reflect.DeepEqual(want, units) {
+		t.Errorf("units:\n%v", strings.Join(pretty.Diff(want, units), "\n"))
+	}
+}
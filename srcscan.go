@@ -1,45 +1,157 @@
 package srcscan
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"go/build"
-	"os"
 	"path/filepath"
+	"sort"
 )
 
 // Config specifies options for Scan.
 type Config struct {
 	// Base is the base directory that all source unit paths are made relative to. Paths within the
 	// concrete source unit structs are relative to the source unit path, not Base. If Base is the
-	// empty string, the current working directory is used.
+	// empty string, the current working directory is used (or, if FileSystem is set, the root of
+	// the FileSystem).
 	Base string
 
+	// FileSystem is the file system to scan. If nil, the operating system's file system (OS) is
+	// used.
+	FileSystem FileSystem
+
 	// Profiles is the list of profiles to use when scanning for source units. If nil,
 	// AllProfiles is used.
 	Profiles []Profile
 
-	// SkipDirs is a list of names of directories that are skipped while scanning.
+	// MarkedDirs maps the slash-separated paths of directories, relative to the scanned
+	// directory, to the names of profiles. Each such directory is a source unit of the named
+	// profile even if the profile's Dir matcher doesn't match it, as long as the profile is
+	// evaluated in the directory (it is not skipped, for instance). Only profiles with a Dir
+	// matcher may be named, and their Unit func must not depend on the files that the Dir
+	// matcher looks for: of AllProfiles, the Python, Go and Ruby app profiles can be used, but
+	// the profiles whose units are read from a manifest (those with Profile.ReadsManifest set,
+	// such as "NPM package", which needs a package.json) can't. To give a directory a unit type
+	// of its own (such as "scripts"), name a profile declared in Profiles that creates
+	// GenericUnits.
+	MarkedDirs map[string]string
+
+	// RepoConfig, if true, indicates that the RepoConfig in the file RepoConfigFile in the
+	// scanned directory, if there is one, should be merged over this Config.
+	RepoConfig bool
+
+	// SkipDirs is a list of patterns of directories that are skipped while scanning and omitted
+	// from source units. Patterns are matched against the slash-separated path of each directory
+	// relative to the scanned directory, using the syntax of gitignore(5): a pattern without a
+	// slash (such as "node_modules") matches a directory of that name at any depth, and a pattern
+	// with a leading or inner slash (such as "/third_party" or "web/build") is anchored at the
+	// scanned directory. "*", "?" and character classes match within a path component, and "**"
+	// matches any number of components, so "**/node_modules" and "/third_party/**" are also
+	// accepted. A pattern prefixed with "!" re-includes directories matched by an earlier
+	// pattern, including directories below a skipped one (such as "!vendor/keep"). Profiles may
+	// skip further directories (see Profile.SkipDirs).
 	SkipDirs []string
 
+	// RespectGitIgnore, if true, indicates that files and directories ignored by git should be
+	// skipped while scanning and omitted from source units. The patterns in .gitignore files in
+	// all directories (including, on the OS file system, the directories above the scanned
+	// directory up to the root of its git repository) and in .git/info/exclude are honored, as
+	// described in gitignore(5).
+	RespectGitIgnore bool
+
+	// FollowSymlinks, if true, indicates that symbolic links to directories should be followed
+	// while scanning the OS file system (FileSystem must be nil). Links are not followed if they
+	// resolve to a directory outside the scanned directory, or to a directory that is already
+	// being walked (as determined by os.SameFile, which compares device and inode numbers on Unix
+	// systems); such links are listed in Result.SkippedLinks. Source units that were reached
+	// through a link record it (see Symlinked).
+	FollowSymlinks bool
+
 	// PathIndependent, if true, indicates that all filesystem paths should be relativized, if
 	// possible, or else cleared.
 	PathIndependent bool
 
+	// Concurrency is the maximum number of directories that are read, and source units that are
+	// constructed, in parallel. If it is 0 or 1, directories are read one at a time and source
+	// units are constructed one at a time. The order of the results of Scan does not depend on
+	// Concurrency.
+	Concurrency int
+
+	// KeepGoing, if true, indicates that directories and files that can't be read, and source units
+	// that can't be created, should be recorded as errors in the result and skipped, rather than
+	// aborting the scan.
+	KeepGoing bool
+
+	// MaxDepth, if positive, is the maximum depth below the scanned directory of the directories
+	// that are scanned. Deeper directories are not read, and are listed in Result.Truncated.
+	MaxDepth int
+
+	// MaxEntries, if positive, is the maximum number of files and directories that are visited
+	// while walking the scanned directory. Once it is reached, the remaining entries of each
+	// directory being walked are not scanned, and the directories are listed in
+	// Result.Truncated. Which entries are visited depends on the scheduling of the walk if
+	// Concurrency is greater than 1.
+	MaxEntries int
+
+	// MaxFilesPerUnit, if positive, is the maximum number of files that are listed in a source
+	// unit whose files are collected by walking its directory (such as the *Files fields of
+	// NPMPackage). Further files are omitted, and the unit is listed in Result.Truncated.
+	MaxFilesPerUnit int
+
+	// MaxManifestSize, if positive, is the maximum size in bytes of a manifest file (such as
+	// package.json) that is read into a source unit. Larger manifests are omitted, and the unit is
+	// listed in Result.Truncated.
+	MaxManifestSize int64
+
+	// Stats, if true, indicates that statistics about the scan should be collected in
+	// Result.Stats.
+	Stats bool
+
 	NPMPackage NPMPackageConfig
 	GoPackage  GoPackageConfig
 	Ruby       RubyConfig
+
+	// ctx is the context of the scan in progress, if any. Unit readers check it (with ctxErr) so
+	// that they stop promptly when the scan is canceled.
+	ctx context.Context
+
+	// scanDir is the scanned directory of the scan in progress, if any.
+	scanDir string
+
+	// ignore determines the files ignored in the scan in progress, if RespectGitIgnore is set.
+	ignore *ignorer
+
+	// skip determines the directories skipped in the scan in progress, per SkipDirs.
+	skip *skipper
+
+	// truncate records that the source unit being read was truncated because of the limit
+	// described by its argument.
+	truncate func(error)
+
+	// stats holds the statistics of the scan in progress, if Stats is set.
+	stats *Stats
 }
 
-func (c Config) skipDir(name string) bool {
-	for _, dirToSkip := range c.SkipDirs {
-		if name == dirToSkip {
-			return true
-		}
+// Context returns the context of the scan in progress (as given to ScanContext, for instance), or
+// context.Background() if there is none. A Profile's Unit func, which is passed the Config of the
+// scan, can use it to stop promptly when the scan is canceled.
+func (c Config) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
 	}
-	return false
+	return c.ctx
+}
+
+// ctxErr returns the error of the context of the scan in progress, if it is done.
+func (c Config) ctxErr() error {
+	return c.Context().Err()
 }
 
 var Default = Config{
-	SkipDirs: []string{"node_modules", "vendor", "testdata", "site-packages", "bower_components"},
+	// Directories of packages installed by package managers are skipped for every profile; other
+	// conventions (such as Go's vendor and testdata directories) are in Profile.SkipDirs.
+	SkipDirs: []string{"node_modules", "bower_components", "site-packages"},
 	NPMPackage: NPMPackageConfig{
 		TestDirs:          []string{"test", "tests", "spec", "specs", "unit", "mocha", "karma"},
 		TestSuffixes:      []string{"test.js", "tests.js", "spec.js", "specs.js"},
@@ -67,74 +179,175 @@ func Scan(dir string) (found []Unit, err error) {
 }
 
 // Scan walks the directory tree at dir, looking for source units that match profiles in the
-// configuration. Scan returns a list of all source units found.
+// configuration. Scan returns a list of all source units found. If a directory can't be read or
+// a source unit can't be created, Scan returns a *ScanError; if c.KeepGoing is set, Scan instead
+// returns the units it found along with a ScanErrors listing each such failure. Otherwise, if a
+// limit such as c.MaxDepth was reached, Scan returns the units it found along with a ScanErrors
+// listing the truncations (see Result.Truncated).
+//
+// The tree is walked once, and each directory is read once; all profiles are evaluated against
+// each directory listing and file. Units are returned grouped by profile (in the order of the
+// profiles) and, within each profile, in walk order, regardless of c.Concurrency.
 func (c Config) Scan(dir string) (found []Unit, err error) {
-	var profiles []Profile
-	if c.Profiles != nil {
-		profiles = c.Profiles
-	} else {
-		profiles = AllProfiles
+	return c.ScanContext(context.Background(), dir)
+}
+
+// ScanContext is like Scan, but it stops scanning once ctx is done. It then returns ctx.Err()
+// along with the source units that were found before the scan stopped.
+func (c Config) ScanContext(ctx context.Context, dir string) (found []Unit, err error) {
+	r, err := c.ScanResultContext(ctx, dir)
+	if r == nil {
+		return nil, err
+	}
+	if err == nil {
+		err = r.err()
 	}
+	return r.Units, err
+}
 
-	c.Base, _ = filepath.Abs(c.Base)
-
-	skipFiles := false
-	for _, profile := range profiles {
-		err = filepath.Walk(dir, func(path string, info os.FileInfo, inerr error) (err error) {
-			if inerr != nil {
-				return inerr
-			}
-			if info.IsDir() {
-				if dir != path && c.skipDir(info.Name()) {
-					return filepath.SkipDir
-				}
-
-				var dirh *os.File
-				dirh, err = os.Open(path)
-				if err != nil {
-					return
-				}
-				defer dirh.Close()
-
-				var filenames []string
-				filenames, err = dirh.Readdirnames(0)
-				if err != nil {
-					return
-				}
-
-				if profile.Dir != nil && profile.Dir.DirMatches(path, filenames) {
-					relpath, abspath := c.relAbsPath(path)
-					found = append(found, profile.Unit(abspath, relpath, c, info))
-					if profile.TopLevelOnly {
-						return filepath.SkipDir
-					}
-					// skip trying to match the files if gems or apps are found
-					if profile.Name == "Ruby Gem" || profile.Name == "Ruby app" {
-						skipFiles = true
-					}
-				}
-			} else {
-				if !skipFiles && profile.File != nil && profile.File.FileMatches(path) {
-					relpath, abspath := c.relAbsPath(path)
-					found = append(found, profile.Unit(abspath, relpath, c, info))
-				}
-			}
-			return
-		})
+// A Result is the outcome of a scan.
+type Result struct {
+	// Units is the list of source units found, in the order described in Config.Scan.
+	Units []Unit
+
+	// Errors lists the directories and files that could not be scanned, in walk order. It is only
+	// populated if Config.KeepGoing is set (otherwise the first such error aborts the scan).
+	Errors ScanErrors
+
+	// SkippedLinks lists the symbolic links that were not followed, in walk order. The Err of
+	// each is ErrSymlinkEscape or ErrSymlinkCycle.
+	SkippedLinks ScanErrors
+
+	// Truncated lists the directories that were not fully scanned, and the source units that
+	// were not fully read, because a limit in the Config was reached, in walk order. The Err of
+	// each is ErrMaxDepth, ErrMaxEntries, ErrMaxFilesPerUnit or ErrMaxManifestSize; the Profile
+	// of each source unit is set.
+	Truncated ScanErrors
+
+	// Stats holds statistics about the scan, if Config.Stats is set.
+	Stats *Stats
+}
+
+// err returns the errors in r as an error for Scan and ScanFunc, or nil if there are none.
+// Truncations are returned if there are no other errors, so that they aren't silently ignored.
+func (r *Result) err() error {
+	if len(r.Errors) > 0 {
+		return r.Errors
 	}
+	if len(r.Truncated) > 0 {
+		return r.Truncated
+	}
+	return nil
+}
 
-	return
+// Errors recorded in Result.SkippedLinks.
+var (
+	ErrSymlinkEscape = errors.New("symbolic link resolves outside the scanned directory")
+	ErrSymlinkCycle  = errors.New("symbolic link resolves to a directory that contains it")
+)
+
+// ScanResult is like Scan, but it returns a Result that holds the non-fatal errors encountered
+// alongside the units found.
+func (c Config) ScanResult(dir string) (*Result, error) {
+	return c.ScanResultContext(context.Background(), dir)
 }
 
-func (c Config) relAbsPath(path string) (rel string, abs string) {
-	abs, err := filepath.Abs(path)
+// ScanResultContext is like ScanResult, but it stops scanning once ctx is done. It then returns
+// ctx.Err() along with a Result holding what was found before the scan stopped.
+func (c Config) ScanResultContext(ctx context.Context, dir string) (*Result, error) {
+	c, err := c.withRepoConfig(dir)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
+	}
+	s := c.newScanner()
+	var all []built
+	err = s.run(ctx, dir, func(b built) error {
+		all = append(all, b)
+		return nil
+	})
+	if err != nil && err != ctx.Err() {
+		return nil, err
 	}
 
-	rel, err = filepath.Rel(c.Base, abs)
+	sort.Sort(builtInWalkOrder(all))
+	r := &Result{Errors: s.errs, SkippedLinks: s.skippedLinks, Truncated: s.truncated, Stats: s.config.stats}
+	for _, b := range all {
+		r.Units = append(r.Units, b.unit)
+	}
+	sort.Stable(r.Errors)
+	sort.Stable(r.SkippedLinks)
+	sort.Stable(r.Truncated)
+	return r, err
+}
+
+// ScanFunc walks the directory tree at dir like Scan, but instead of returning the source units
+// found, it calls fn for each one as soon as it has been created. Calls to fn are not concurrent,
+// but (unless c.Concurrency is 0 or 1) their order is not deterministic. If fn returns an error,
+// the scan stops and ScanFunc returns that error.
+func (c Config) ScanFunc(dir string, fn func(Unit) error) error {
+	return c.ScanFuncContext(context.Background(), dir, fn)
+}
+
+// ScanFuncContext is like ScanFunc, but it stops scanning and returns ctx.Err() once ctx is
+// done.
+func (c Config) ScanFuncContext(ctx context.Context, dir string, fn func(Unit) error) error {
+	c, err := c.withRepoConfig(dir)
 	if err != nil {
-		panic(err.Error())
+		return err
+	}
+	s := c.newScanner()
+	err = s.run(ctx, dir, func(b built) error { return fn(b.unit) })
+	if err != nil {
+		return err
+	}
+	r := &Result{Errors: s.errs, Truncated: s.truncated}
+	sort.Stable(r.Errors)
+	sort.Stable(r.Truncated)
+	return r.err()
+}
+
+// ScanError records an error encountered while scanning a directory or file.
+type ScanError struct {
+	Path    string // path of the directory or file being scanned
+	Profile string // name of the profile whose source unit was being read, or "" if none
+	Err     error  // the underlying error
+}
+
+func (e *ScanError) Error() string {
+	if e.Profile == "" {
+		return "scan " + e.Path + ": " + e.Err.Error()
+	}
+	return "scan " + e.Path + " (" + e.Profile + "): " + e.Err.Error()
+}
+
+// ScanErrors is a list of errors encountered during a scan with Config.KeepGoing set, or of
+// truncations. It implements sort.Interface, ordering errors by path in walk order.
+type ScanErrors []*ScanError
+
+func (e ScanErrors) Error() string {
+	switch len(e) {
+	case 0:
+		return "no errors"
+	case 1:
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e[0], len(e)-1)
+}
+
+func (e ScanErrors) Len() int           { return len(e) }
+func (e ScanErrors) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e ScanErrors) Less(i, j int) bool { return walkOrderLess(e[i].Path, e[j].Path) }
+
+func (c Config) relAbsPath(path string) (rel string, abs string, err error) {
+	if c.FileSystem == nil {
+		abs, err = filepath.Abs(path)
+		if err != nil {
+			return
+		}
+	} else {
+		abs = filepath.Clean(path)
 	}
+
+	rel, err = filepath.Rel(c.Base, abs)
 	return
 }
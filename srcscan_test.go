@@ -1,9 +1,13 @@
 package srcscan
 
 import (
+	"context"
+	"errors"
 	"github.com/kr/pretty"
 	"go/build"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
@@ -76,6 +80,9 @@ func TestScan(t *testing.T) {
 				},
 				&JavaProject{
 					Dir:              "java-maven",
+					GroupID:          "com.example",
+					ArtifactID:       "example",
+					Version:          "0.0.1-SNAPSHOT",
 					ProjectClasspath: "target/classes",
 					SrcFiles:         []string{"src/main/java/foo/Foo.java"},
 					TestFiles:        []string{"src/test/java/bar/Bar.java"},
@@ -93,8 +100,8 @@ func TestScan(t *testing.T) {
 					PackageJSON: []byte(`{"name":"subpkg"}`),
 					LibFiles:    []string{"a.js"},
 				},
-				&PythonModule{"python/myscript.py"},
-				&PythonPackage{"python/mypkg"},
+				&PythonModule{File: "python/myscript.py"},
+				&PythonPackage{Dir: "python/mypkg"},
 				&RubyApp{
 					Dir:       "ruby/sample_app",
 					SrcFiles:  []string{"app/app.rb"},
@@ -119,11 +126,11 @@ func TestScan(t *testing.T) {
 						TopLevelOnly: false,
 						Dir:          FileInDir{"__init__.py"},
 						File:         FileHasSuffix{".py"},
-						Unit: func(abspath, relpath string, config Config, info os.FileInfo) Unit {
+						Unit: func(abspath, relpath string, config Config, info os.FileInfo) (Unit, error) {
 							if info.IsDir() {
-								return &PythonPackage{relpath}
+								return &PythonPackage{Dir: relpath}, nil
 							} else {
-								return &PythonModule{relpath}
+								return &PythonModule{File: relpath}, nil
 							}
 						},
 					},
@@ -131,12 +138,12 @@ func TestScan(t *testing.T) {
 			},
 			dir: "testdata/python",
 			units: []Unit{
-				&PythonModule{"python/mypkg/__init__.py"},
-				&PythonModule{"python/mypkg/a.py"},
-				&PythonModule{"python/mypkg/qux/__init__.py"},
-				&PythonModule{"python/myscript.py"},
-				&PythonPackage{"python/mypkg"},
-				&PythonPackage{"python/mypkg/qux"},
+				&PythonModule{File: "python/mypkg/__init__.py"},
+				&PythonModule{File: "python/mypkg/a.py"},
+				&PythonModule{File: "python/mypkg/qux/__init__.py"},
+				&PythonModule{File: "python/myscript.py"},
+				&PythonPackage{Dir: "python/mypkg"},
+				&PythonPackage{Dir: "python/mypkg/qux"},
 			},
 		},
 	}
@@ -169,3 +176,286 @@ func TestScan(t *testing.T) {
 		}
 	}
 }
+
+func TestScan_Concurrency(t *testing.T) {
+	config := Default
+	config.Base = "testdata"
+	want, err := config.Scan("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{2, 8} {
+		config.Concurrency = n
+		units, err := config.Scan("testdata")
+		if err != nil {
+			t.Errorf("Concurrency %d: got error %q", n, err)
+			continue
+		}
+		if !reflect.DeepEqual(want, units) {
+			t.Errorf("Concurrency %d: units differ from sequential scan:\n%v", n, strings.Join(pretty.Diff(want, units), "\n"))
+		}
+	}
+}
+
+func TestScan_Error(t *testing.T) {
+	dir, err := ioutil.TempDir("", "srcscan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A directory named package.json can't be read as an NPM package manifest.
+	if err := os.MkdirAll(filepath.Join(dir, "pkg", "package.json"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Default
+	config.Base = dir
+	units, err := config.Scan(dir)
+	if units != nil {
+		t.Errorf("got units %v, want nil", units)
+	}
+	serr, ok := err.(*ScanError)
+	if !ok {
+		t.Fatalf("got error %v (%T), want *ScanError", err, err)
+	}
+	if want := filepath.Join(dir, "pkg"); serr.Path != want {
+		t.Errorf("got Path %q, want %q", serr.Path, want)
+	}
+	if want := "NPM package"; serr.Profile != want {
+		t.Errorf("got Profile %q, want %q", serr.Profile, want)
+	}
+}
+
+func TestScan_KeepGoing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "srcscan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A directory named package.json can't be read as an NPM package manifest.
+	if err := os.MkdirAll(filepath.Join(dir, "a", "package.json"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "b"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "b", "package.json"), []byte(`{}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Default
+	config.Base = dir
+	config.KeepGoing = true
+	r, err := config.ScanResult(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Unit{&NPMPackage{Dir: "b", PackageJSON: []byte(`{}`)}}
+	if !reflect.DeepEqual(r.Units, want) {
+		t.Errorf("got units %v, want %v", r.Units, want)
+	}
+	if len(r.Errors) != 1 || r.Errors[0].Path != filepath.Join(dir, "a") {
+		t.Errorf("got errors %v, want 1 error for %s", r.Errors, filepath.Join(dir, "a"))
+	}
+
+	units, err := config.Scan(dir)
+	if len(units) != 1 {
+		t.Errorf("got %d units, want 1", len(units))
+	}
+	if _, ok := err.(ScanErrors); !ok {
+		t.Errorf("got error %v (%T), want ScanErrors", err, err)
+	}
+}
+
+func TestScanFunc(t *testing.T) {
+	config := Default
+	config.Base = "testdata"
+	want, err := config.Scan("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var units []Unit
+	err = config.ScanFunc("testdata", func(unit Unit) error {
+		units = append(units, unit)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Sort(Units(units))
+	sort.Sort(Units(want))
+	if !reflect.DeepEqual(want, units) {
+		t.Errorf("units:\n%v", strings.Join(pretty.Diff(want, units), "\n"))
+	}
+
+	// Stop early.
+	errStop := errors.New("stop")
+	n := 0
+	err = config.ScanFunc("testdata", func(unit Unit) error {
+		n++
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("got error %v, want %v", err, errStop)
+	}
+	if n != 1 {
+		t.Errorf("got %d calls, want 1", n)
+	}
+}
+
+func TestScanContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	units, err := Default.ScanContext(ctx, "testdata")
+	if err != context.Canceled {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	if len(units) != 0 {
+		t.Errorf("got %d units, want none", len(units))
+	}
+
+	// Cancel the scan after the first unit is found.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	n := 0
+	err = Default.ScanFuncContext(ctx, "testdata", func(unit Unit) error {
+		n++
+		cancel()
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	if n != 1 {
+		t.Errorf("got %d units, want 1", n)
+	}
+}
+
+func TestConfig_Context(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "v")
+	config := Default
+	config.Base = ""
+	config.FileSystem = MapFS(map[string]string{"a/x": ""})
+	config.Profiles = []Profile{{
+		Name: "dir",
+		Dir:  FileInDir{"x"},
+		Unit: func(abspath, relpath string, config Config, info os.FileInfo) (Unit, error) {
+			if v := config.Context().Value(key{}); v != "v" {
+				t.Errorf("got context value %v, want %q", v, "v")
+			}
+			return &PythonPackage{Dir: relpath}, nil
+		},
+	}}
+	if _, err := config.ScanContext(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	if Default.Context() != context.Background() {
+		t.Error("got a context other than context.Background() outside of a scan")
+	}
+}
+
+func TestScan_FollowSymlinks(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "srcscan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	dir := filepath.Join(tmp, "root")
+	for _, d := range []string{"root/shared/pkg", "outside/pkg"} {
+		if err := os.MkdirAll(filepath.Join(tmp, d), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tmp, d, "package.json"), []byte(`{}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		"root/app/pkg":         "../shared/pkg",  // followed
+		"root/shared/pkg/loop": ".",              // cycle
+		"root/out":             "../outside/pkg", // escapes root
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(tmp, link)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, filepath.Join(tmp, link)); err != nil {
+			t.Skip("can't create symlinks:", err)
+		}
+	}
+
+	config := Default
+	config.Base = dir
+	config.Profiles = AllProfiles[:1]
+
+	r, err := config.ScanResult(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Units) != 1 || r.Units[0].Path() != "shared/pkg" {
+		t.Errorf("without FollowSymlinks: got units %+v, want only shared/pkg", r.Units)
+	}
+
+	config.FollowSymlinks = true
+	r, err = config.ScanResult(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, unit := range r.Units {
+		paths = append(paths, unit.Path())
+	}
+	if want := []string{"app/pkg", "shared/pkg"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got unit paths %v, want %v", paths, want)
+	}
+	var links []string
+	for _, unit := range r.Units {
+		links = append(links, UnitSymlink(unit))
+	}
+	if want := []string{"app/pkg", ""}; !reflect.DeepEqual(links, want) {
+		t.Errorf("got unit symlinks %q, want %q", links, want)
+	}
+	var skipped []string
+	for _, e := range r.SkippedLinks {
+		rel, _ := filepath.Rel(dir, e.Path)
+		skipped = append(skipped, rel+": "+e.Err.Error())
+	}
+	want := []string{
+		"app/pkg/loop: " + ErrSymlinkCycle.Error(),
+		"out: " + ErrSymlinkEscape.Error(),
+		"shared/pkg/loop: " + ErrSymlinkCycle.Error(),
+	}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Errorf("got SkippedLinks %v, want %v", skipped, want)
+	}
+}
+
+func TestScan_Owns(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.PathIndependent = true
+	config.FileSystem = MapFS(map[string]string{
+		"gem/x.gemspec":     "",
+		"gem/lib/x.rb":      "",
+		"gem/bin/tool.rb":   "",
+		"scripts/deploy.rb": "",
+	})
+
+	units, err := config.Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The gem owns the Ruby files below it, but not those elsewhere.
+	want := []Unit{
+		&RubyGem{Dir: "gem", Name: "x", GemSpecFile: "x.gemspec", SrcFiles: []string{"lib/x.rb"}},
+		&RubyFile{File: "scripts/deploy.rb"},
+	}
+	if !reflect.DeepEqual(want, units) {
+		t.Errorf("units:\n%v", strings.Join(pretty.Diff(want, units), "\n"))
+	}
+}
@@ -0,0 +1,79 @@
+package srcscan
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// Stats describes the work done by a scan. It is collected if Config.Stats is set.
+type Stats struct {
+	Dirs      int64         // number of directories read by the walk of the scanned directory
+	Files     int64         // number of files (other than directories) visited by the walk
+	BytesRead int64         // number of bytes read from files, including by unit readers
+	Duration  time.Duration // wall-clock duration of the scan
+
+	// Profiles holds the statistics of each profile, in the order of Config.Profiles.
+	Profiles []ProfileStats
+}
+
+// ProfileStats describes the work done for a profile during a scan.
+type ProfileStats struct {
+	Name string
+
+	Matches int           // number of directories and files that matched the profile
+	Units   int           // number of source units created
+	Errors  int           // number of source units that could not be created
+	Time    time.Duration // total time spent creating source units (in Profile.Unit)
+}
+
+func newStats(profiles []Profile) *Stats {
+	st := &Stats{Profiles: make([]ProfileStats, len(profiles))}
+	for i, profile := range profiles {
+		st.Profiles[i].Name = profile.Name
+	}
+	return st
+}
+
+// String formats the statistics as a table, with a line for each profile that matched anything.
+func (st *Stats) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d dirs, %d files, %d bytes read in %s\n", st.Dirs, st.Files, st.BytesRead, st.Duration)
+	tw := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "profile\tmatches\tunits\terrors\ttime\n")
+	for _, p := range st.Profiles {
+		if p.Matches > 0 {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n", p.Name, p.Matches, p.Units, p.Errors, p.Time)
+		}
+	}
+	tw.Flush()
+	return buf.String()
+}
+
+// countingFS is a FileSystem that counts the bytes read from it.
+type countingFS struct {
+	FileSystem
+	stats *Stats
+}
+
+func (fs countingFS) Open(path string) (io.ReadCloser, error) {
+	f, err := fs.FileSystem.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return countingReader{f, &fs.stats.BytesRead}, nil
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n *int64
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddInt64(r.n, int64(n))
+	return n, err
+}
@@ -0,0 +1,52 @@
+package srcscan
+
+import (
+	"testing"
+)
+
+func TestScan_Stats(t *testing.T) {
+	config := Default
+	config.Base = ""
+	config.Stats = true
+	config.FileSystem = MapFS(map[string]string{
+		"npm/package.json": `{"name":"x"}`,
+		"npm/lib/a.js":     "",
+		"py/a.py":          "",
+		"py/b.py":          "",
+	})
+
+	r, err := config.ScanResult(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := r.Stats
+	if st == nil {
+		t.Fatal("got nil Stats")
+	}
+	if want := int64(4); st.Dirs != want {
+		t.Errorf("got Dirs %d, want %d", st.Dirs, want)
+	}
+	if want := int64(4); st.Files != want {
+		t.Errorf("got Files %d, want %d", st.Files, want)
+	}
+	if want := int64(len(`{"name":"x"}`)); st.BytesRead != want {
+		t.Errorf("got BytesRead %d, want %d", st.BytesRead, want)
+	}
+	counts := map[string][2]int{}
+	for _, p := range st.Profiles {
+		counts[p.Name] = [2]int{p.Matches, p.Units}
+	}
+	if want := [2]int{1, 1}; counts["NPM package"] != want {
+		t.Errorf("got NPM package matches and units %v, want %v", counts["NPM package"], want)
+	}
+	if want := [2]int{2, 2}; counts["Python package and module"] != want {
+		t.Errorf("got Python matches and units %v, want %v", counts["Python package and module"], want)
+	}
+
+	config.Stats = false
+	if r, err = config.ScanResult("."); err != nil {
+		t.Fatal(err)
+	} else if r.Stats != nil {
+		t.Errorf("got Stats %v, want nil", r.Stats)
+	}
+}
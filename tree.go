@@ -0,0 +1,62 @@
+package srcscan
+
+import (
+	"path/filepath"
+)
+
+// A UnitNode is a source unit in a tree of source units (see Tree).
+type UnitNode struct {
+	Unit     Unit
+	Parent   *UnitNode   // the nearest enclosing unit, or nil if none encloses Unit
+	Children []*UnitNode // the units whose nearest enclosing unit is Unit, in the order given to Tree
+}
+
+// Tree arranges units in a tree by their paths, and returns the roots of the tree (the units that
+// no other unit encloses). A unit encloses the units whose paths are in its directory or its
+// subdirectories; the parent of each unit is the unit with the longest such path. If several
+// units have the same path, the first of them is the parent of the units they enclose, and they
+// have the same parent. Roots and children are in the order of units.
+func Tree(units []Unit) []*UnitNode {
+	nodes := make([]*UnitNode, len(units))
+	byPath := make(map[string]*UnitNode, len(units))
+	for i, unit := range units {
+		nodes[i] = &UnitNode{Unit: unit}
+		if p := filepath.Clean(unit.Path()); byPath[p] == nil {
+			byPath[p] = nodes[i]
+		}
+	}
+
+	var roots []*UnitNode
+	for _, node := range nodes {
+		for p := filepath.Clean(node.Unit.Path()); p != "." && p != filepath.Dir(p); {
+			p = filepath.Dir(p)
+			if parent := byPath[p]; parent != nil {
+				node.Parent = parent
+				break
+			}
+		}
+		if node.Parent == nil {
+			roots = append(roots, node)
+		} else {
+			node.Parent.Children = append(node.Parent.Children, node)
+		}
+	}
+	return roots
+}
+
+// Tree arranges the units found in a tree (see Tree).
+func (r *Result) Tree() []*UnitNode {
+	return Tree(r.Units)
+}
+
+// Walk calls fn for n and each of its descendants, in depth-first order, with their depth below n.
+func (n *UnitNode) Walk(fn func(node *UnitNode, depth int)) {
+	n.walk(fn, 0)
+}
+
+func (n *UnitNode) walk(fn func(node *UnitNode, depth int), depth int) {
+	fn(n, depth)
+	for _, child := range n.Children {
+		child.walk(fn, depth+1)
+	}
+}
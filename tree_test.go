@@ -0,0 +1,52 @@
+package srcscan
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestTree(t *testing.T) {
+	units := []Unit{
+		&NPMPackage{Dir: "npm"},
+		&NPMPackage{Dir: "npm/subpkg"},
+		&BowerComponent{Dir: "npm"},
+		&PythonModule{File: "python/myscript.py"},
+		&PythonPackage{Dir: "python/mypkg"},
+		&PythonPackage{Dir: "python/mypkg/qux"},
+		&GoPackage{},
+		&RubyFile{File: "npm/lib/deep/x.rb"},
+	}
+	units[6].(*GoPackage).Dir = "go/cmd/mycmd"
+
+	var got []string
+	for _, root := range Tree(units) {
+		root.Walk(func(node *UnitNode, depth int) {
+			parent := "<nil>"
+			if node.Parent != nil {
+				parent = node.Parent.Unit.Path()
+			}
+			got = append(got, fmt.Sprintf("%d %s < %s", depth, node.Unit.Path(), parent))
+		})
+	}
+	want := []string{
+		"0 npm < <nil>",
+		"1 npm/subpkg < npm",
+		"1 npm/lib/deep/x.rb < npm",
+		"0 npm < <nil>",
+		"0 python/myscript.py < <nil>",
+		"0 python/mypkg < <nil>",
+		"1 python/mypkg/qux < python/mypkg",
+		"0 go/cmd/mycmd < <nil>",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got tree %q, want %q", got, want)
+	}
+}
+
+func TestTree_Root(t *testing.T) {
+	roots := Tree([]Unit{&NPMPackage{Dir: "a"}, &NPMPackage{Dir: "."}})
+	if len(roots) != 1 || roots[0].Unit.Path() != "." || len(roots[0].Children) != 1 {
+		t.Errorf("got roots %v, want the unit at . enclosing the unit at a", roots)
+	}
+}
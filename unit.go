@@ -3,14 +3,14 @@ package srcscan
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"go/build"
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 // Unit represents a "source unit," such as a Go package, a node.js package, or a Python package.
@@ -20,8 +20,48 @@ type Unit interface {
 	Path() string
 }
 
+// Symlinked records the symbolic link through which a source unit was reached, if
+// Config.FollowSymlinks is set. The unit types in this package embed it, and custom unit types may
+// embed it too.
+type Symlinked struct {
+	// Symlink is the path of the symbolic link through which the unit was reached, relative to
+	// Config.Base, or "" if it wasn't reached through one. If the unit was reached through
+	// several links, the outermost one is given.
+	Symlink string `json:",omitempty"`
+}
+
+func (s *Symlinked) setSymlink(path string) { s.Symlink = path }
+func (s *Symlinked) symlink() string        { return s.Symlink }
+
+// symlinker is implemented by source units that embed Symlinked.
+type symlinker interface {
+	setSymlink(path string)
+	symlink() string
+}
+
+// UnitSymlink returns the path of the symbolic link through which unit was reached (see
+// Symlinked), or "" if it wasn't reached through one or doesn't record it.
+func UnitSymlink(unit Unit) string {
+	if u, ok := unit.(symlinker); ok {
+		return u.symlink()
+	}
+	return ""
+}
+
+// UnitType returns the name that the type of unit is registered under (see RegisterUnitType), or
+// the name of the type if it is not registered.
 func UnitType(unit Unit) string {
-	return reflect.TypeOf(unit).Elem().Name()
+	t := reflect.TypeOf(unit)
+	unitTypes.RLock()
+	name, ok := unitTypes.names[t]
+	unitTypes.RUnlock()
+	if ok {
+		return name
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
 }
 
 // Units implements sort.Interface.
@@ -29,8 +69,14 @@ type Units []Unit
 
 func (u Units) Len() int      { return len(u) }
 func (u Units) Swap(i, j int) { u[i], u[j] = u[j], u[i] }
+
+// Less orders units by type (see UnitType), and then by path. To order units by ID, use SortByID.
 func (u Units) Less(i, j int) bool {
-	return fmt.Sprintf("%T", u[i])+u[i].Path() < fmt.Sprintf("%T", u[j])+u[j].Path()
+	ti, tj := UnitType(u[i]), UnitType(u[j])
+	if ti != tj {
+		return ti < tj
+	}
+	return u[i].Path() < u[j].Path()
 }
 
 // NPMPackage represents an NPM package.
@@ -44,6 +90,7 @@ type NPMPackage struct {
 	TestFiles      []string        `json:",omitempty"`
 	VendorFiles    []string        `json:",omitempty"`
 	GeneratedFiles []string        `json:",omitempty"`
+	Symlinked
 }
 
 // Path returns the directory containing the package.json file.
@@ -63,22 +110,40 @@ type NPMPackageConfig struct {
 	VendorDirs        []string
 }
 
-func readNPMPackage(absdir, reldir string, config Config, info os.FileInfo) Unit {
+func readNPMPackage(absdir, reldir string, config Config, info os.FileInfo) (Unit, error) {
 	u := &NPMPackage{Dir: reldir}
 
 	// Read package.json.
 	var err error
-	u.PackageJSON, err = ioutil.ReadFile(filepath.Join(absdir, "package.json"))
+	u.PackageJSON, err = readManifest(config, absdir, "package.json")
 	if err != nil {
-		panic("read package.json: " + err.Error())
+		return nil, err
 	}
 
-	// Populate *Files fields.
+	// Populate *Files fields. Files in vendor directories that are skipped by default are listed as
+	// VendorFiles, but all other SkipDirs patterns apply.
 	c := config.NPMPackage
-	err = filepath.Walk(absdir, func(path string, info os.FileInfo, inerr error) (err error) {
+	config.skip = config.skip.without(npmVendorSkipDirs(c.VendorDirs))
+	lim := &fileLimiter{config: config}
+	err = walk(config.fs(), absdir, func(path string, info os.FileInfo, inerr error) (err error) {
+		if inerr != nil {
+			return inerr
+		}
+		if err := config.ctxErr(); err != nil {
+			return err
+		}
+		relpath, _ := filepath.Rel(absdir, path)
+		parts := strings.Split(relpath, "/")
+		if info.IsDir() && config.pruned(path) {
+			return filepath.SkipDir
+		}
+		if config.skipped(path, info.IsDir()) {
+			return
+		}
 		if info.Mode().IsRegular() && strings.HasSuffix(info.Name(), ".js") {
-			relpath, _ := filepath.Rel(absdir, path)
-			parts := strings.Split(relpath, "/")
+			if !lim.add() {
+				return errStopWalk
+			}
 			// Prioritize detection of vendored and generated files, marking
 			// them as such even if they are in an example dir.
 			for _, part := range parts {
@@ -112,22 +177,43 @@ func readNPMPackage(absdir, reldir string, config Config, info os.FileInfo) Unit
 			}
 
 			// Don't traverse into sub-packages.
-			if path != absdir && dirHasFile(path, "package.json") {
-				return filepath.SkipDir
+			if path != absdir {
+				var isPkg bool
+				isPkg, err = dirHasFile(config.fs(), path, "package.json")
+				if isPkg {
+					return filepath.SkipDir
+				}
 			}
 		}
 		return
 	})
 	if err != nil {
-		panic("scan files: " + err.Error())
+		return nil, err
 	}
-	return u
+	return u, nil
+}
+
+// defaultVendorSkipDirs are the SkipDirs patterns of Default and of the "NPM package" profile that
+// name package manager and vendor directories.
+var defaultVendorSkipDirs = []string{"node_modules", "bower_components", "vendor"}
+
+// npmVendorSkipDirs returns the patterns in defaultVendorSkipDirs that name one of vendorDirs,
+// whose files the NPM package reader lists as VendorFiles instead of skipping them.
+func npmVendorSkipDirs(vendorDirs []string) []string {
+	var dirs []string
+	for _, dir := range defaultVendorSkipDirs {
+		if contains(vendorDirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
 }
 
 // BowerComponent represents a node.js package.
 type BowerComponent struct {
 	Dir       string
 	BowerJSON json.RawMessage `json:",omitempty"`
+	Symlinked
 }
 
 // Path returns the directory containing the bower.json file.
@@ -135,22 +221,23 @@ func (u *BowerComponent) Path() string {
 	return u.Dir
 }
 
-func readBowerComponent(absdir, reldir string, config Config, info os.FileInfo) Unit {
+func readBowerComponent(absdir, reldir string, config Config, info os.FileInfo) (Unit, error) {
 	u := &BowerComponent{Dir: reldir}
 
 	// Read bower.json.
 	var err error
-	u.BowerJSON, err = ioutil.ReadFile(filepath.Join(absdir, "bower.json"))
+	u.BowerJSON, err = readManifest(config, absdir, "bower.json")
 	if err != nil {
-		panic("read bower.json: " + err.Error())
+		return nil, err
 	}
 
-	return u
+	return u, nil
 }
 
 // GoPackage represents a Go package.
 type GoPackage struct {
 	build.Package
+	Symlinked
 }
 
 type GoPackageConfig struct {
@@ -162,23 +249,42 @@ func (u *GoPackage) Path() string {
 	return u.Dir
 }
 
-func readGoPackage(absdir, reldir string, config Config, info os.FileInfo) Unit {
+func readGoPackage(absdir, reldir string, config Config, info os.FileInfo) (Unit, error) {
 	u := &GoPackage{}
 	c := config.GoPackage
-	pkg, err := c.BuildContext.ImportDir(absdir, 0)
+	buildContext := c.BuildContext
+	if config.FileSystem != nil || config.ignore != nil || config.stats != nil {
+		fs := config.fs()
+		buildContext.IsDir = func(path string) bool { return isDir(fs, path) }
+		buildContext.ReadDir = fs.ReadDir
+		buildContext.OpenFile = fs.Open
+	}
+	if config.FileSystem != nil {
+		buildContext.HasSubdir = func(root, dir string) (string, bool) { return "", false }
+	}
+	pkg, err := buildContext.ImportDir(absdir, 0)
 	if err != nil {
 		log.Printf("Warning: error encountered while importing Go package at %s: %s", absdir, err)
 	}
 
-	// Try to determine the import path for the package. (Adapted from go/build.)
-	srcdirs := c.BuildContext.SrcDirs()
+	// Try to determine the import path for the package, from the nearest go.mod or else from
+	// the GOPATH. (Adapted from go/build.) Paths in a FileSystem other than OS are not in any
+	// GOPATH.
+	importPath, inModule := goModuleImportPath(config.fs(), absdir)
+	if inModule {
+		pkg.ImportPath = importPath
+	}
+	var srcdirs []string
+	if config.FileSystem == nil && !inModule {
+		srcdirs = c.BuildContext.SrcDirs()
+	}
 	for i, root := range srcdirs {
 		if sub, ok := hasSubdir(root, absdir); ok {
 			// We found a potential import path for dir,
 			// but check that using it wouldn't find something
 			// else first.
 			for _, earlyRoot := range srcdirs[:i] {
-				if subsrcdir := filepath.Join(earlyRoot, "src", sub); isDir(subsrcdir) {
+				if subsrcdir := filepath.Join(earlyRoot, "src", sub); isDir(OS, subsrcdir) {
 					goto Found
 				}
 			}
@@ -202,12 +308,59 @@ Found:
 
 	u.Package = *pkg
 	u.Package.Dir = reldir
-	return u
+	return u, nil
+}
+
+// goModuleImportPath returns the import path of the package in dir, which is the path of the
+// module declared in the nearest go.mod in dir or one of its ancestors, joined with the path of
+// dir relative to the module's directory. It returns false if there is no such go.mod.
+func goModuleImportPath(fs FileSystem, dir string) (string, bool) {
+	var rel []string
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if ok, _ := dirHasFile(fs, d, "go.mod"); ok {
+			data, err := readFile(fs, filepath.Join(d, "go.mod"))
+			if err != nil {
+				return "", false
+			}
+			modpath := goModulePath(data)
+			if modpath == "" {
+				return "", false
+			}
+			for i := len(rel) - 1; i >= 0; i-- {
+				modpath += "/" + rel[i]
+			}
+			return modpath, true
+		}
+		if parent := filepath.Dir(d); parent == d {
+			return "", false
+		}
+		rel = append(rel, filepath.Base(d))
+	}
+}
+
+// goModulePath returns the module path in the module directive of the go.mod file data, or "" if
+// there is none.
+func goModulePath(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		f := strings.Fields(line)
+		if len(f) != 2 || f[0] != "module" {
+			continue
+		}
+		if path, err := strconv.Unquote(f[1]); err == nil {
+			return path
+		}
+		return f[1]
+	}
+	return ""
 }
 
 // PythonPackage represents a Python package.
 type PythonPackage struct {
 	Dir string
+	Symlinked
 }
 
 // Path returns the directory immediately containing the Python package.
@@ -218,6 +371,7 @@ func (u *PythonPackage) Path() string {
 // PythonPackage represents a Python package.
 type PythonModule struct {
 	File string
+	Symlinked
 }
 
 func (u *PythonModule) Path() string {
@@ -239,6 +393,8 @@ type RubyGem struct {
 	GemSpecFile string
 	SrcFiles    []string
 	TestFiles   []string
+	Deps        []Dependency `json:",omitempty"` // declared dependencies (see Dependencies)
+	Symlinked
 }
 
 // Path returns the Ruby Gem's root directory (which contains the *.gemspec file).
@@ -246,12 +402,21 @@ func (u *RubyGem) Path() string {
 	return u.Dir
 }
 
-func collectRubyFiles(absdir, basedir string) (files []string, err error) {
-	err = filepath.Walk(basedir, func(path string, info os.FileInfo, inerr error) (err error) {
+func collectRubyFiles(config Config, lim *fileLimiter, absdir, basedir string) (files []string, err error) {
+	err = walk(config.fs(), basedir, func(path string, info os.FileInfo, inerr error) (err error) {
 		if inerr != nil {
 			return
 		}
-		if info.Mode().IsRegular() && strings.HasSuffix(info.Name(), ".rb") {
+		if err := config.ctxErr(); err != nil {
+			return err
+		}
+		if info.IsDir() && config.pruned(path) {
+			return filepath.SkipDir
+		}
+		if info.Mode().IsRegular() && strings.HasSuffix(info.Name(), ".rb") && !config.skipped(path, false) {
+			if !lim.add() {
+				return errStopWalk
+			}
 			relpath, _ := filepath.Rel(absdir, path)
 			files = append(files, relpath)
 		}
@@ -260,49 +425,50 @@ func collectRubyFiles(absdir, basedir string) (files []string, err error) {
 	return
 }
 
-func readRubyGem(absdir, reldir string, config Config, info os.FileInfo) Unit {
+func readRubyGem(absdir, reldir string, config Config, info os.FileInfo) (Unit, error) {
 	gem := RubyGem{Dir: reldir}
 
-	dirh, err := os.Open(absdir)
-	if err != nil {
-		panic("Open " + absdir + ": " + err.Error())
-	}
-	defer dirh.Close()
-	filenames, err := dirh.Readdirnames(0)
+	infos, err := config.fs().ReadDir(absdir)
 	if err != nil {
-		panic("Readdirnames " + absdir + ": " + err.Error())
+		return nil, err
 	}
-	for _, filename := range filenames {
-		if strings.HasSuffix(filename, ".gemspec") {
+	for _, info := range infos {
+		if filename := info.Name(); strings.HasSuffix(filename, ".gemspec") {
 			gem.GemSpecFile = filename
 			gem.Name = strings.TrimSuffix(filename, ".gemspec")
 		}
 	}
 	if gem.GemSpecFile == "" {
-		panic("No .gemspec found in " + absdir)
+		return nil, errors.New("no .gemspec found in " + absdir)
 	}
 
+	lim := &fileLimiter{config: config}
 	for _, srcdir := range config.Ruby.GemSrcDirs {
-		if dir := filepath.Join(absdir, srcdir); isDir(dir) {
-			files, err := collectRubyFiles(absdir, dir)
+		if dir := filepath.Join(absdir, srcdir); isDir(config.fs(), dir) {
+			files, err := collectRubyFiles(config, lim, absdir, dir)
 			if err != nil {
-				panic("scan SrcFiles: " + err.Error())
+				return nil, err
 			}
 			gem.SrcFiles = append(gem.SrcFiles, files...)
 		}
 	}
 
 	for _, testdir := range config.Ruby.TestDirs {
-		if dir := filepath.Join(absdir, testdir); isDir(dir) {
-			files, err := collectRubyFiles(absdir, dir)
+		if dir := filepath.Join(absdir, testdir); isDir(config.fs(), dir) {
+			files, err := collectRubyFiles(config, lim, absdir, dir)
 			if err != nil {
-				panic("scan TestFiles: " + err.Error())
+				return nil, err
 			}
 			gem.TestFiles = append(gem.TestFiles, files...)
 		}
 	}
 
-	return &gem
+	gem.Deps, err = readRubyDependencies(config, absdir, gem.GemSpecFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gem, nil
 }
 
 // RubyApp represents a Ruby App.
@@ -310,6 +476,8 @@ type RubyApp struct {
 	Dir       string
 	SrcFiles  []string
 	TestFiles []string
+	Deps      []Dependency `json:",omitempty"` // declared dependencies (see Dependencies)
+	Symlinked
 }
 
 // Path returns the Ruby App's root directory (which contains the *.appspec file).
@@ -317,35 +485,43 @@ func (u *RubyApp) Path() string {
 	return u.Dir
 }
 
-func readRubyApp(absdir, reldir string, config Config, info os.FileInfo) Unit {
+func readRubyApp(absdir, reldir string, config Config, info os.FileInfo) (Unit, error) {
 	app := RubyApp{Dir: reldir}
+	lim := &fileLimiter{config: config}
 
 	for _, srcdir := range config.Ruby.AppSrcDirs {
-		if dir := filepath.Join(absdir, srcdir); isDir(dir) {
-			files, err := collectRubyFiles(absdir, dir)
+		if dir := filepath.Join(absdir, srcdir); isDir(config.fs(), dir) {
+			files, err := collectRubyFiles(config, lim, absdir, dir)
 			if err != nil {
-				panic("scan SrcFiles: " + err.Error())
+				return nil, err
 			}
 			app.SrcFiles = append(app.SrcFiles, files...)
 		}
 	}
 
 	for _, testdir := range config.Ruby.TestDirs {
-		if dir := filepath.Join(absdir, testdir); isDir(dir) {
-			files, err := collectRubyFiles(absdir, dir)
+		if dir := filepath.Join(absdir, testdir); isDir(config.fs(), dir) {
+			files, err := collectRubyFiles(config, lim, absdir, dir)
 			if err != nil {
-				panic("scan TestFiles: " + err.Error())
+				return nil, err
 			}
 			app.TestFiles = append(app.TestFiles, files...)
 		}
 	}
 
-	return &app
+	var err error
+	app.Deps, err = readRubyDependencies(config, absdir, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &app, nil
 }
 
-// individual Ruby file (collected when there is no Gemfile)
+// individual Ruby file (collected when it is not owned by a Ruby Gem or Ruby app)
 type RubyFile struct {
 	File string
+	Symlinked
 }
 
 func (u *RubyFile) Path() string {
@@ -355,9 +531,14 @@ func (u *RubyFile) Path() string {
 // JavaProject represents a Java project.
 type JavaProject struct {
 	Dir              string
+	GroupID          string `json:",omitempty"` // from the pom.xml (or its parent)
+	ArtifactID       string `json:",omitempty"` // from the pom.xml
+	Version          string `json:",omitempty"` // from the pom.xml (or its parent)
 	ProjectClasspath string
 	SrcFiles         []string
 	TestFiles        []string
+	Deps             []Dependency `json:",omitempty"` // declared dependencies (see Dependencies)
+	Symlinked
 }
 
 // Path returns the directory that immediately contains the Maven pom.xml.
@@ -365,19 +546,29 @@ func (u *JavaProject) Path() string {
 	return u.Dir
 }
 
-func readJavaMavenProject(absdir, reldir string, config Config, info os.FileInfo) Unit {
+func readJavaMavenProject(absdir, reldir string, config Config, info os.FileInfo) (Unit, error) {
 	u := &JavaProject{
 		Dir:              reldir,
 		ProjectClasspath: "target/classes",
 	}
 	srcdir, testdir := "src/main/java", "src/test/java"
+	lim := &fileLimiter{config: config}
 
 	var collectJavaFiles = func(basedir string) (files []string, err error) {
-		err = filepath.Walk(basedir, func(path string, info os.FileInfo, inerr error) (err error) {
+		err = walk(config.fs(), basedir, func(path string, info os.FileInfo, inerr error) (err error) {
 			if inerr != nil {
 				return
 			}
-			if info.Mode().IsRegular() && strings.HasSuffix(info.Name(), ".java") {
+			if err := config.ctxErr(); err != nil {
+				return err
+			}
+			if info.IsDir() && config.pruned(path) {
+				return filepath.SkipDir
+			}
+			if info.Mode().IsRegular() && strings.HasSuffix(info.Name(), ".java") && !config.skipped(path, false) {
+				if !lim.add() {
+					return errStopWalk
+				}
 				relpath, _ := filepath.Rel(absdir, path)
 				files = append(files, relpath)
 			}
@@ -389,14 +580,19 @@ func readJavaMavenProject(absdir, reldir string, config Config, info os.FileInfo
 	var err error
 	u.SrcFiles, err = collectJavaFiles(filepath.Join(absdir, srcdir))
 	if err != nil {
-		panic("scan SrcFiles: " + err.Error())
+		return nil, err
 	}
 	u.TestFiles, err = collectJavaFiles(filepath.Join(absdir, testdir))
 	if err != nil {
-		panic("scan TestFiles: " + err.Error())
+		return nil, err
+	}
+	pom, err := readPOM(config, absdir)
+	if err != nil {
+		return nil, err
 	}
+	u.GroupID, u.ArtifactID, u.Version, u.Deps = pom.GroupID, pom.ArtifactID, pom.Version, pom.dependencies()
 
-	return u
+	return u, nil
 }
 
 type MarshalableUnit struct {
@@ -428,36 +624,61 @@ func (mu *MarshalableUnit) UnmarshalJSON(data []byte) (err error) {
 var _ json.Marshaler = &MarshalableUnit{}
 var _ json.Unmarshaler = &MarshalableUnit{}
 
-// UnmarshalJSON attempts to unmarshal JSON data into a new source unit struct of type unitType.
+// UnmarshalJSON attempts to unmarshal JSON data into a new source unit struct of type unitType,
+// which must be registered (see RegisterUnitType).
 func UnmarshalJSON(data []byte, unitType string) (unit Unit, err error) {
-	switch unitType {
-	case "NPMPackage":
-		unit = &NPMPackage{}
-	case "BowerComponent":
-		unit = &BowerComponent{}
-	case "GoPackage":
-		unit = &GoPackage{}
-	case "PythonPackage":
-		unit = &PythonPackage{}
-	case "PythonModule":
-		unit = &PythonModule{}
-	case "RubyApp":
-		unit = &RubyApp{}
-	case "RubyGem":
-		unit = &RubyGem{}
-	case "RubyFile":
-		unit = &RubyFile{}
-	case "JavaProject":
-		unit = &JavaProject{}
-	default:
-		err = errors.New("unhandled source unit type: " + unitType)
-	}
-	if err == nil {
-		err = json.Unmarshal(data, &unit)
+	unitTypes.RLock()
+	newUnit, ok := unitTypes.byName[unitType]
+	unitTypes.RUnlock()
+	if !ok {
+		return nil, errors.New("unhandled source unit type: " + unitType)
 	}
+	unit = newUnit()
+	err = json.Unmarshal(data, unit)
 	return
 }
 
+var unitTypes = struct {
+	sync.RWMutex
+	byName map[string]func() Unit
+	names  map[reflect.Type]string
+}{byName: make(map[string]func() Unit), names: make(map[reflect.Type]string)}
+
+// RegisterUnitType registers a source unit type under name, so that UnitType returns name for
+// units of the type and UnmarshalJSON (and MarshalableUnit) can unmarshal them. newUnit returns a
+// new, empty unit of the type, which must be a pointer. Profiles that create their own types of
+// source units should register them in an init function. RegisterUnitType panics if name or the
+// type is already registered.
+func RegisterUnitType(name string, newUnit func() Unit) {
+	t := reflect.TypeOf(newUnit())
+	if t == nil || t.Kind() != reflect.Ptr {
+		panic("srcscan: RegisterUnitType " + name + ": unit is not a pointer")
+	}
+	unitTypes.Lock()
+	defer unitTypes.Unlock()
+	if _, dup := unitTypes.byName[name]; dup {
+		panic("srcscan: RegisterUnitType called twice for name " + name)
+	}
+	if prev, dup := unitTypes.names[t]; dup {
+		panic("srcscan: RegisterUnitType " + name + ": type already registered as " + prev)
+	}
+	unitTypes.byName[name] = newUnit
+	unitTypes.names[t] = name
+}
+
+func init() {
+	RegisterUnitType("NPMPackage", func() Unit { return &NPMPackage{} })
+	RegisterUnitType("BowerComponent", func() Unit { return &BowerComponent{} })
+	RegisterUnitType("GoPackage", func() Unit { return &GoPackage{} })
+	RegisterUnitType("PythonPackage", func() Unit { return &PythonPackage{} })
+	RegisterUnitType("PythonModule", func() Unit { return &PythonModule{} })
+	RegisterUnitType("RubyApp", func() Unit { return &RubyApp{} })
+	RegisterUnitType("RubyGem", func() Unit { return &RubyGem{} })
+	RegisterUnitType("RubyFile", func() Unit { return &RubyFile{} })
+	RegisterUnitType("JavaProject", func() Unit { return &JavaProject{} })
+	RegisterUnitType("GenericUnit", func() Unit { return &GenericUnit{} })
+}
+
 // Compile-time interface implementation checks.
 
-var _, _, _, _, _, _, _ Unit = &NPMPackage{}, &BowerComponent{}, &GoPackage{}, &PythonPackage{}, &PythonModule{}, &RubyGem{}, &JavaProject{}
+var _, _, _, _, _, _, _, _ Unit = &NPMPackage{}, &BowerComponent{}, &GoPackage{}, &PythonPackage{}, &PythonModule{}, &RubyGem{}, &JavaProject{}, &GenericUnit{}
@@ -71,3 +71,39 @@ func TestMarshalableUnit(t *testing.T) {
 		}
 	}
 }
+
+type customUnit struct {
+	Dir  string
+	Tags []string
+}
+
+func (u *customUnit) Path() string { return u.Dir }
+
+// The test type is registered once, because RegisterUnitType panics if it is called again (as it
+// would be by a test run with -count=2).
+func init() {
+	RegisterUnitType("custom", func() Unit { return &customUnit{} })
+}
+
+func TestRegisterUnitType(t *testing.T) {
+	unit := &customUnit{Dir: "a", Tags: []string{"x"}}
+	if typ := UnitType(unit); typ != "custom" {
+		t.Errorf("got UnitType %q, want %q", typ, "custom")
+	}
+
+	data, err := json.Marshal(&MarshalableUnit{unit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu MarshalableUnit
+	if err := json.Unmarshal(data, &mu); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mu.Unit, unit) {
+		t.Errorf("got unit %+v, want %+v", mu.Unit, unit)
+	}
+
+	if _, err := UnmarshalJSON(data, "unregistered"); err == nil {
+		t.Error("got no error unmarshaling unregistered unit type")
+	}
+}
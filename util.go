@@ -24,15 +24,15 @@ func hasAnySuffix(suffixes []string, str string) bool {
 	return false
 }
 
-func dirHasFile(dir, filename string) bool {
+func dirHasFile(fs FileSystem, dir, filename string) (bool, error) {
 	path := filepath.Join(dir, filename)
-	info, err := os.Stat(path)
+	info, err := fs.Stat(path)
 	if err != nil && os.IsNotExist(err) {
-		return false
-	} else if err == nil && info.Mode().IsRegular() {
-		return true
+		return false, nil
+	} else if err != nil {
+		return false, err
 	}
-	panic("dirHasFile: " + err.Error())
+	return info.Mode().IsRegular(), nil
 }
 
 func hasSubdir(root, dir string) (rel string, ok bool) {
@@ -54,7 +54,28 @@ func hasSubdir(root, dir string) (rel string, ok bool) {
 	return filepath.ToSlash(dir[len(root):]), true
 }
 
-func isDir(path string) bool {
-	fi, err := os.Stat(path)
+func isDir(fs FileSystem, path string) bool {
+	fi, err := fs.Stat(path)
 	return err == nil && fi.IsDir()
 }
+
+func anyTrue(bs []bool) bool {
+	for _, b := range bs {
+		if b {
+			return true
+		}
+	}
+	return false
+}
+
+// walkOrderLess reports whether filepath.Walk visits path a before path b.
+func walkOrderLess(a, b string) bool {
+	as := strings.Split(filepath.ToSlash(a), "/")
+	bs := strings.Split(filepath.ToSlash(b), "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] != bs[i] {
+			return as[i] < bs[i]
+		}
+	}
+	return len(as) < len(bs)
+}